@@ -2,7 +2,57 @@ package command
 
 import "code.cloudfoundry.org/cli/plugin"
 
+// Exit codes returned by the copy command so that
+// scripts can distinguish between kinds of failures
+const (
+	// ExitOK - The copy completed successfully
+	ExitOK = 0
+	// ExitTotalFailure - The copy failed and nothing was copied
+	ExitTotalFailure = 1
+	// ExitUsageError - The command arguments were invalid
+	ExitUsageError = 2
+	// ExitTargetError - A target could not be found or a session
+	// to it could not be established or authenticated
+	ExitTargetError = 3
+	// ExitDestinationNotFound - The destination org or space does not exist
+	ExitDestinationNotFound = 4
+	// ExitPreflightFailure - Validation of the source artifacts or
+	// the destination failed before anything was copied
+	ExitPreflightFailure = 5
+	// ExitPartialCopy - The copy failed after some artifacts were copied
+	ExitPartialCopy = 6
+	// ExitNotConfirmed - The copy was not confirmed so nothing was copied
+	ExitNotConfirmed = 7
+	// ExitOutOfDate - Copies in the space were found by copy-status
+	// to be out of date with their source
+	ExitOutOfDate = 8
+)
+
+// exitCodeDescription - Describes the given exit code
+func exitCodeDescription(exitCode int) string {
+	switch exitCode {
+	case ExitOK:
+		return "the copy completed successfully"
+	case ExitUsageError:
+		return "the arguments were invalid"
+	case ExitTargetError:
+		return "a target could not be reached"
+	case ExitDestinationNotFound:
+		return "the destination does not exist"
+	case ExitPreflightFailure:
+		return "validation failed before anything was copied"
+	case ExitPartialCopy:
+		return "some artifacts were copied"
+	case ExitNotConfirmed:
+		return "the copy was not confirmed"
+	case ExitOutOfDate:
+		return "copies are out of date"
+	default:
+		return "nothing was copied"
+	}
+}
+
 // CopyCmd - Provides IoC for the Copy Implementation
 type CopyCmd interface {
-	Execute(cli plugin.CliConnection, o *CopyOptions)
+	Execute(cli plugin.CliConnection, o *CopyOptions) int
 }
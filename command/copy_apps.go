@@ -0,0 +1,248 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// prepareDestApplication - Applies the option driven changes to the
+// parameters of an application before it is created at the destination
+// so that the copy is started with them the first time it starts
+func (c *CopyCommand) prepareDestApplication(params *models.AppParams) {
+
+	if params.Name == nil {
+		return
+	}
+	name := *params.Name
+	c.beginAppStep(name, "create")
+
+	if c.o.AppEnv != nil && !c.o.AppEnv.IsEmpty() {
+		srcEnv := map[string]interface{}{}
+		if params.EnvironmentVars != nil {
+			srcEnv = *params.EnvironmentVars
+		}
+		env := c.o.AppEnv.Apply(name, srcEnv)
+		params.EnvironmentVars = &env
+	}
+
+	if c.o.AppScale != nil && !c.o.AppScale.IsEmpty() {
+		app := c.srcAppFields(name, models.ApplicationFields{Name: name})
+		if params.InstanceCount != nil {
+			app.InstanceCount = *params.InstanceCount
+		}
+		if params.Memory != nil {
+			app.Memory = *params.Memory
+		}
+		if params.DiskQuota != nil {
+			app.DiskQuota = *params.DiskQuota
+		}
+		instances, memory, disk := c.o.AppScale.Apply(app)
+		if c.o.AppScale.HasInstances(name) {
+			params.InstanceCount = &instances
+		}
+		if c.o.AppScale.HasMemory(name) {
+			params.Memory = &memory
+		}
+		if c.o.AppScale.HasDisk(name) {
+			params.DiskQuota = &disk
+		}
+	}
+	if newName := c.blueGreenName(name); newName != name {
+		params.Name = &newName
+	}
+	c.logger.DebugMessage("Creating app '%s' with => %# v", name, params)
+}
+
+// destQuota - A quota of the destination and how much of it is in use
+type destQuota struct {
+	scope string
+
+	memoryLimit         int64
+	instanceMemoryLimit int64
+	appInstanceLimit    int
+
+	memoryUsed    int64
+	instancesUsed int
+}
+
+// checkDestQuota - Validates that the applications to be started at the
+// destination fit within what is left of the quotas of the destination
+// space and org once scaled. Applications replaced in place by their
+// copies no longer count as in use, whereas applications that run
+// alongside their copies, as with blue-green or rolling replacements,
+// do. As the quotas and their usage may not be readable by a space
+// developer the check is skipped with a warning if they cannot be read.
+func (c *CopyCommand) checkDestQuota(org models.Organization, space models.Space) (err error) {
+
+	var (
+		ccClient *helpers.CCClient
+		quotas   []destQuota
+		replaced destUsage
+
+		totalMemory    int64
+		totalInstances int
+	)
+
+	if ccClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+		return
+	}
+	if quotas, replaced, err = c.destQuotas(ccClient, org, space); err != nil {
+		c.logger.UI.Warn("The destination quotas could not be checked: %s", err.Error())
+		return nil
+	}
+
+	for _, name := range c.o.SourceAppNames {
+		if !c.startsApp(name) {
+			continue
+		}
+		app := c.srcAppFields(name, models.ApplicationFields{Name: name})
+
+		instances, memory := app.InstanceCount, app.Memory
+		if c.o.AppScale != nil {
+			instances, memory, _ = c.o.AppScale.Apply(app)
+		}
+		for _, q := range quotas {
+			if q.instanceMemoryLimit > 0 && memory > q.instanceMemoryLimit {
+				return fmt.Errorf("The memory of app '%s' of %dM exceeds the destination %s instance memory limit of %dM.",
+					name, memory, q.scope, q.instanceMemoryLimit)
+			}
+		}
+		totalMemory += int64(instances) * memory
+		totalInstances += instances
+	}
+	c.logger.DebugMessage("Memory required at destination => %dM for %d instances", totalMemory, totalInstances)
+	c.logger.DebugMessage("Memory released at destination => %dM for %d instances", replaced.memory, replaced.instances)
+
+	for _, q := range quotas {
+		memoryUsed := q.memoryUsed - replaced.memory
+		instancesUsed := q.instancesUsed - replaced.instances
+		if q.memoryLimit > 0 && memoryUsed+totalMemory > q.memoryLimit {
+			return fmt.Errorf("The copied apps require %dM of memory but only %dM of the destination %s memory limit of %dM is available.",
+				totalMemory, available(q.memoryLimit, memoryUsed), q.scope, q.memoryLimit)
+		}
+		if q.appInstanceLimit > 0 && instancesUsed+totalInstances > q.appInstanceLimit {
+			return fmt.Errorf("The copied apps require %d instances but only %d of the destination %s instance limit of %d are available.",
+				totalInstances, available(int64(q.appInstanceLimit), int64(instancesUsed)), q.scope, q.appInstanceLimit)
+		}
+	}
+	return
+}
+
+// destUsage - Memory and instances in use at the destination
+type destUsage struct {
+	memory    int64
+	instances int
+}
+
+// replacesInPlace - Returns whether an app that exists at the destination
+// is replaced by its copy without running alongside it
+func (c *CopyCommand) replacesInPlace() bool {
+	if c.o.BlueGreen || c.o.Strategy == StrategyRolling {
+		return false
+	}
+	return c.o.AppConflict == "" || c.o.AppConflict == ConflictReplace
+}
+
+// destQuotas - Returns the quota of the destination space if it has
+// one and the quota of the destination org along with their usage
+// and the usage of the started destination apps replaced in place
+func (c *CopyCommand) destQuotas(ccClient *helpers.CCClient, org models.Organization, space models.Space) (quotas []destQuota, replaced destUsage, err error) {
+
+	var (
+		spaceQuota struct {
+			Entity struct {
+				MemoryLimit         int64 `json:"memory_limit"`
+				InstanceMemoryLimit int64 `json:"instance_memory_limit"`
+				AppInstanceLimit    int   `json:"app_instance_limit"`
+			} `json:"entity"`
+		}
+		spaceSummary struct {
+			Apps []struct {
+				Name      string `json:"name"`
+				State     string `json:"state"`
+				Memory    int64  `json:"memory"`
+				Instances int    `json:"instances"`
+			} `json:"apps"`
+		}
+		memoryUsage struct {
+			MemoryUsage int64 `json:"memory_usage_in_mb"`
+		}
+		instanceUsage struct {
+			InstanceUsage int `json:"instance_usage"`
+		}
+	)
+
+	if err = ccClient.Do("GET", fmt.Sprintf("/v2/spaces/%s/summary", space.GUID), nil, &spaceSummary); err != nil {
+		return
+	}
+	var spaceUsage destUsage
+	for _, a := range spaceSummary.Apps {
+		if !strings.EqualFold(a.State, "started") {
+			continue
+		}
+		spaceUsage.memory += int64(a.Instances) * a.Memory
+		spaceUsage.instances += a.Instances
+		if c.replacesInPlace() && containsString(c.o.SourceAppNames, a.Name) {
+			replaced.memory += int64(a.Instances) * a.Memory
+			replaced.instances += a.Instances
+		}
+	}
+
+	if space.SpaceQuotaGUID != "" {
+		if err = ccClient.Do("GET", "/v2/space_quota_definitions/"+space.SpaceQuotaGUID, nil, &spaceQuota); err != nil {
+			return
+		}
+		quotas = append(quotas, destQuota{
+			scope:               "space",
+			memoryLimit:         spaceQuota.Entity.MemoryLimit,
+			instanceMemoryLimit: spaceQuota.Entity.InstanceMemoryLimit,
+			appInstanceLimit:    spaceQuota.Entity.AppInstanceLimit,
+			memoryUsed:          spaceUsage.memory,
+			instancesUsed:       spaceUsage.instances,
+		})
+	}
+
+	q := destQuota{
+		scope:               "org",
+		memoryLimit:         org.QuotaDefinition.MemoryLimit,
+		instanceMemoryLimit: org.QuotaDefinition.InstanceMemoryLimit,
+		appInstanceLimit:    org.QuotaDefinition.AppInstanceLimit,
+	}
+	if q.memoryLimit > 0 {
+		if err = ccClient.Do("GET", fmt.Sprintf("/v2/organizations/%s/memory_usage", org.GUID), nil, &memoryUsage); err != nil {
+			return
+		}
+		q.memoryUsed = memoryUsage.MemoryUsage
+	}
+	if q.appInstanceLimit > 0 {
+		if err = ccClient.Do("GET", fmt.Sprintf("/v2/organizations/%s/instance_usage", org.GUID), nil, &instanceUsage); err != nil {
+			return
+		}
+		q.instancesUsed = instanceUsage.InstanceUsage
+	}
+	quotas = append(quotas, q)
+
+	c.logger.DebugMessage("Destination quotas => %# v", quotas)
+	return
+}
+
+func available(limit, used int64) int64 {
+	if used > limit {
+		return 0
+	}
+	return limit - used
+}
+
+// srcAppFields - Returns the fields of the named source
+// application or the given default if it was not found
+func (c *CopyCommand) srcAppFields(name string, defaultFields models.ApplicationFields) models.ApplicationFields {
+	for _, a := range c.srcApps {
+		if a.Name == name {
+			return a.ApplicationFields
+		}
+	}
+	return defaultFields
+}
@@ -0,0 +1,366 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/cli/cf/flags"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// copyEnvPrefix - The prefix of environment variables that
+// set options i.e. CF_COPY_HOST_FORMAT for --host-format
+const copyEnvPrefix = "CF_COPY_"
+
+// positionalOptions - Names of the positional arguments when
+// they are given in a profile or as environment variables
+var positionalOptions = []string{"space", "org", "target"}
+
+// copyArgs - Looks up the value of an option in the command line flags
+// first, then in the CF_COPY_* environment variables, then in the options
+// of the stage promoted to or the planned copy and finally in the selected
+// profile of the copy configuration file
+type copyArgs struct {
+	flags     flags.FlagContext
+	env       map[string][]string
+	overrides map[string][]string
+	profile   map[string][]string
+
+	config    string
+	protected []helpers.ProtectedDestination
+}
+
+// newCopyArgs - Collects the options set by the environment and the
+// profile named by the --profile flag or CF_COPY_PROFILE. The profile
+// and the protected destinations are read from the file named by the
+// --config flag, CF_COPY_CONFIG or the copy configuration file in the
+// current directory.
+func newCopyArgs(f flags.FlagContext) (a *copyArgs, err error) {
+
+	var values map[string]interface{}
+
+	a = &copyArgs{
+		flags:     f,
+		env:       make(map[string][]string),
+		overrides: make(map[string][]string),
+		profile:   make(map[string][]string),
+	}
+
+	for _, name := range optionNames() {
+		if isFlagOnly(name) {
+			continue
+		}
+		envName := copyEnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+		if value, ok := os.LookupEnv(envName); ok {
+			if a.env[name], err = normalizeOption(name, value); err != nil {
+				return nil, fmt.Errorf("invalid value '%s' for %s", value, envName)
+			}
+		}
+	}
+
+	a.config = a.String("config")
+	if a.config == "" {
+		a.config = helpers.FindCopyConfig()
+	}
+	if a.config != "" {
+		if a.protected, err = helpers.ReadProtectedDestinations(a.config); err != nil {
+			return nil, err
+		}
+	}
+
+	profile := a.String("profile")
+	if profile == "" {
+		return
+	}
+	if a.config == "" {
+		return nil, fmt.Errorf("The profile '%s' cannot be used as no %s file was found.", profile, helpers.CopyConfigFile)
+	}
+	if values, err = helpers.ReadCopyProfile(a.config, profile); err != nil {
+		return
+	}
+	if a.profile, err = configOptions(values, fmt.Sprintf("profile '%s'", profile)); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// withOverrides - Returns the args with the given options of the stage
+// promoted to or a planned copy. The options cannot set the source or
+// destination or any of the given excluded options.
+func (a *copyArgs) withOverrides(values map[string]interface{}, source string, excluded ...string) (*copyArgs, error) {
+
+	var err error
+
+	excluded = append(excluded, positionalOptions...)
+	excluded = append(excluded, "source-space", "source-org", "source-target", "to")
+	for _, name := range excluded {
+		if _, ok := values[name]; ok {
+			return nil, fmt.Errorf("the %s option cannot be set in %s", name, source)
+		}
+	}
+
+	args := *a
+	if args.overrides, err = configOptions(values, source); err != nil {
+		return nil, err
+	}
+	return &args, nil
+}
+
+// configOptions - Validates and normalizes option values read
+// from the given part of the copy configuration file
+func configOptions(values map[string]interface{}, source string) (map[string][]string, error) {
+
+	var err error
+
+	options := make(map[string][]string)
+	for name, value := range values {
+		if name == "config" || name == "profile" || !isOption(name) {
+			return nil, fmt.Errorf("unknown option '%s' in %s", name, source)
+		}
+		if name == "allow-protected" {
+			// Copying to a protected destination must be
+			// allowed explicitly each time it is done
+			return nil, fmt.Errorf("the allow-protected option cannot be set in %s", source)
+		}
+		if options[name], err = normalizeOption(name, value); err != nil {
+			return nil, fmt.Errorf("invalid value '%v' for option '%s' in %s", value, name, source)
+		}
+	}
+	return options, nil
+}
+
+// IsSet - Returns whether the option is set by any source
+func (a *copyArgs) IsSet(name string) bool {
+	_, ok := a.lookup(name)
+	return ok
+}
+
+// String - Returns the value of a string option
+func (a *copyArgs) String(name string) string {
+	if a.flags.IsSet(name) {
+		return a.flags.String(name)
+	}
+	values, _ := a.lookup(name)
+	return strings.Join(values, ",")
+}
+
+// Bool - Returns the value of a boolean option
+func (a *copyArgs) Bool(name string) bool {
+	if a.flags.IsSet(name) {
+		return a.flags.Bool(name)
+	}
+	values, _ := a.lookup(name)
+	return len(values) == 1 && values[0] == "true"
+}
+
+// StringSlice - Returns the values of an option that may be repeated
+func (a *copyArgs) StringSlice(name string) []string {
+	if a.flags.IsSet(name) {
+		return a.flags.StringSlice(name)
+	}
+	values, _ := a.lookup(name)
+	return values
+}
+
+// positional - Returns the value of a positional argument
+// that was not given on the command line
+func (a *copyArgs) positional(name string) string {
+	values, _ := a.lookup(name)
+	return strings.Join(values, ",")
+}
+
+func (a *copyArgs) lookup(name string) ([]string, bool) {
+	if a.flags != nil && a.flags.IsSet(name) {
+		return nil, true
+	}
+	if values, ok := a.env[name]; ok {
+		return values, true
+	}
+	if values, ok := a.overrides[name]; ok {
+		return values, true
+	}
+	values, ok := a.profile[name]
+	return values, ok
+}
+
+// optionNames - Returns the names of all options including the
+// positional arguments
+func optionNames() []string {
+	names := append([]string{}, positionalOptions...)
+	for _, f := range copyFlags {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// isFlagOnly - Returns whether the option can only be given on the
+// command line. Skipping the confirmation of a copy or copying to a
+// protected destination must be asked for explicitly each time so
+// they are not taken from the environment.
+func isFlagOnly(name string) bool {
+	return name == "force" || name == "allow-protected"
+}
+
+func isOption(name string) bool {
+	for _, n := range optionNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func optionKind(name string) int {
+	for _, f := range copyFlags {
+		if f.name == name {
+			return f.kind
+		}
+	}
+	return stringOption
+}
+
+// normalizeOption - Converts a value from the environment or a profile
+// to the values of the option. Lists given for string options are
+// joined with commas and boolean options must be true or false.
+func normalizeOption(name string, value interface{}) ([]string, error) {
+
+	values := []string{}
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+	case string:
+		if optionKind(name) == sliceOption {
+			// Environment variables give repeated values one per line
+			values = strings.Split(strings.TrimSpace(v), "\n")
+		} else {
+			values = []string{v}
+		}
+	default:
+		values = []string{fmt.Sprint(v)}
+	}
+
+	switch optionKind(name) {
+	case boolOption:
+		if len(values) != 1 {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		b, err := strconv.ParseBool(values[0])
+		if err != nil {
+			return nil, err
+		}
+		values[0] = strconv.FormatBool(b)
+	case stringOption:
+		values = []string{strings.Join(values, ",")}
+	}
+	return values, nil
+}
+
+// splitArgs - Separates the positional arguments from the flags and
+// their values so that they can be given in any order. Unknown flags
+// are reported with the closest known flag as a suggestion.
+func splitArgs(args []string) (positionals, flagArgs, errs []string) {
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			positionals = append(positionals, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if n := strings.Index(name, "="); n >= 0 {
+			name, value, hasValue = name[:n], name[n+1:], true
+		}
+
+		flag, ok := findFlag(name)
+		if !ok {
+			message := fmt.Sprintf("Unknown option '%s'.", arg)
+			if suggestion := suggestFlag(name); suggestion != "" {
+				message += fmt.Sprintf(" Did you mean '--%s'?", suggestion)
+			}
+			errs = append(errs, message)
+			continue
+		}
+
+		switch {
+		case flag.kind == boolOption && hasValue:
+			errs = append(errs, fmt.Sprintf("The --%s option does not take a value.", flag.name))
+		case flag.kind == boolOption:
+			flagArgs = append(flagArgs, "--"+flag.name)
+		case hasValue:
+			flagArgs = append(flagArgs, "--"+flag.name, value)
+		case i+1 < len(args):
+			i++
+			flagArgs = append(flagArgs, "--"+flag.name, args[i])
+		default:
+			errs = append(errs, fmt.Sprintf("The --%s option requires a value.", flag.name))
+		}
+	}
+	return
+}
+
+func findFlag(name string) (flag copyFlag, ok bool) {
+	for _, f := range copyFlags {
+		if f.name == name || (f.short != "" && f.short == name) {
+			return f, true
+		}
+	}
+	return
+}
+
+// suggestFlag - Returns the known flag that the given unknown flag was
+// most likely meant to be. This is the only flag it is a prefix of or
+// the flag closest to it by edit distance if that is close enough.
+func suggestFlag(name string) string {
+
+	prefixed := []string{}
+	for _, f := range copyFlags {
+		if strings.HasPrefix(f.name, name) {
+			prefixed = append(prefixed, f.name)
+		}
+	}
+	if len(prefixed) == 1 {
+		return prefixed[0]
+	}
+
+	suggestion, best := "", len(name)/3+2
+	for _, f := range copyFlags {
+		if d := editDistance(name, f.name); d < best {
+			suggestion, best = f.name, d
+		}
+	}
+	return suggestion
+}
+
+// editDistance - Returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j] + 1
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,224 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+)
+
+// Suffixes of the names of the copies of existing destination
+// applications while they are rolled out and of the existing
+// applications once they are replaced
+const (
+	blueGreenNewSuffix = "-copy"
+	blueGreenOldSuffix = "-venerable"
+)
+
+// blueGreenApp - An existing destination application that
+// is replaced by its copy without interrupting traffic
+type blueGreenApp struct {
+	name    string
+	newName string
+	oldName string
+	oldApp  models.Application
+
+	oldRenamed bool
+}
+
+// prepareBlueGreen - The copies of existing destination applications are
+// created under a temporary name so the existing applications continue
+// to serve their routes under their own names until their copies are
+// healthy or the copies are rolled out to them. The names are made
+// unique with the time of the copy and are checked for all applications
+// before anything is changed.
+func (c *CopyCommand) prepareBlueGreen() (err error) {
+
+	var destApps []models.Application
+
+	c.blueGreenApps = []blueGreenApp{}
+	if !c.o.BlueGreen && c.o.Strategy != StrategyRolling {
+		return
+	}
+
+	if destApps, err = c.destCCSession.AppSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	destNames := make(map[string]bool)
+	for _, a := range destApps {
+		destNames[a.Name] = true
+	}
+
+	suffix := time.Now().Format("20060102150405")
+	blueGreenApps := []blueGreenApp{}
+	for _, name := range c.o.SourceAppNames {
+		for _, a := range destApps {
+			if a.Name == name {
+				// An intermediate copy is only staged once it is started
+				// so a stopped app has no droplet to roll out
+				if c.o.Strategy == StrategyRolling && !c.startsApp(name) {
+					return fmt.Errorf("The app '%s' cannot be rolled out with --strategy rolling as it is stopped at the source.", name)
+				}
+				bg := blueGreenApp{
+					name:    name,
+					newName: fmt.Sprintf("%s%s-%s", name, blueGreenNewSuffix, suffix),
+					oldName: fmt.Sprintf("%s%s-%s", name, blueGreenOldSuffix, suffix),
+					oldApp:  a,
+				}
+				if destNames[bg.newName] || (c.o.BlueGreen && destNames[bg.oldName]) {
+					return fmt.Errorf("The app '%s' cannot be replaced as an app named '%s' or '%s' already exists at the destination.",
+						name, bg.newName, bg.oldName)
+				}
+				blueGreenApps = append(blueGreenApps, bg)
+				break
+			}
+		}
+	}
+	c.blueGreenApps = blueGreenApps
+	return
+}
+
+// blueGreenName - Returns the name the copy of the application with
+// the given name is created with
+func (c *CopyCommand) blueGreenName(name string) string {
+	for _, bg := range c.blueGreenApps {
+		if bg.name == name {
+			return bg.newName
+		}
+	}
+	return name
+}
+
+// completeBlueGreen - Waits for the copied applications to be healthy,
+// moves the routes of the applications they replace to them and then
+// deletes the replaced applications or stops them if they are kept
+func (c *CopyCommand) completeBlueGreen() (err error) {
+
+	var (
+		health   []appHealth
+		newApp   models.Application
+		destApps []models.Application
+	)
+
+	if !c.o.BlueGreen || len(c.blueGreenApps) == 0 {
+		return
+	}
+
+	names := []string{}
+	for _, bg := range c.blueGreenApps {
+		names = append(names, bg.name)
+	}
+	c.logger.UI.Say("Waiting for replacement apps to start...")
+	if health, err = c.waitForApplications(names, c.startTimeout()); err != nil {
+		return
+	}
+	for _, h := range health {
+		if !h.isHealthy() {
+			return fmt.Errorf("The replacement for app '%s' did not become healthy.", h.name)
+		}
+	}
+
+	if destApps, err = c.destCCSession.AppSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	for len(c.blueGreenApps) > 0 {
+		bg := &c.blueGreenApps[0]
+
+		newApp = models.Application{}
+		for _, h := range health {
+			if h.name == bg.name {
+				newApp.GUID = h.guid
+				break
+			}
+		}
+		for _, a := range destApps {
+			if a.GUID == newApp.GUID {
+				newApp = a
+				break
+			}
+		}
+
+		for _, r := range bg.oldApp.Routes {
+			mapped := false
+			for _, nr := range newApp.Routes {
+				if nr.GUID == r.GUID {
+					mapped = true
+					break
+				}
+			}
+			if !mapped {
+				c.logger.UI.Say("Mapping route %s to app %s...",
+					terminal.EntityNameColor(r.URL()), terminal.EntityNameColor(bg.newName))
+				if err = c.destCCSession.Routes().Bind(r.GUID, newApp.GUID); err != nil {
+					return
+				}
+			}
+			c.logger.UI.Say("Unmapping route %s from app %s...",
+				terminal.EntityNameColor(r.URL()), terminal.EntityNameColor(bg.name))
+			if err = c.destCCSession.Routes().Unbind(r.GUID, bg.oldApp.GUID); err != nil {
+				return
+			}
+		}
+
+		c.logger.UI.Say("Renaming replaced app %s to %s...",
+			terminal.EntityNameColor(bg.name), terminal.EntityNameColor(bg.oldName))
+		if _, err = c.destCCSession.Applications().Update(bg.oldApp.GUID, models.AppParams{Name: &bg.oldName}); err != nil {
+			return
+		}
+		bg.oldRenamed = true
+		c.logger.UI.Say("Renaming app %s to %s...",
+			terminal.EntityNameColor(bg.newName), terminal.EntityNameColor(bg.name))
+		if _, err = c.destCCSession.Applications().Update(newApp.GUID, models.AppParams{Name: &bg.name}); err != nil {
+			return
+		}
+
+		if c.o.KeepOld {
+			stopped := "stopped"
+			c.logger.UI.Say("Stopping replaced app %s...", terminal.EntityNameColor(bg.oldName))
+			if _, err = c.destCCSession.Applications().Update(bg.oldApp.GUID, models.AppParams{State: &stopped}); err != nil {
+				return
+			}
+		} else {
+			c.logger.UI.Say("Deleting replaced app %s...", terminal.EntityNameColor(bg.oldName))
+			if err = c.destCCSession.Applications().Delete(bg.oldApp.GUID); err != nil {
+				return
+			}
+		}
+		c.blueGreenApps = c.blueGreenApps[1:]
+	}
+	return
+}
+
+// rollbackBlueGreen - Restores the applications replaced by a copy
+// that did not complete by deleting their copies and mapping their
+// routes and restoring their names if they were changed
+func (c *CopyCommand) rollbackBlueGreen() {
+
+	if len(c.blueGreenApps) == 0 {
+		return
+	}
+
+	for _, bg := range c.blueGreenApps {
+		c.logger.UI.Say("Restoring app %s...", terminal.EntityNameColor(bg.name))
+
+		if newApp, err := c.destCCSession.Applications().Read(bg.name); err == nil && newApp.GUID != bg.oldApp.GUID {
+			if err = c.destCCSession.Applications().Delete(newApp.GUID); err != nil {
+				c.logger.UI.Warn("Unable to delete copy of app '%s': %s", bg.name, err.Error())
+				continue
+			}
+		}
+		for _, r := range bg.oldApp.Routes {
+			if err := c.destCCSession.Routes().Bind(r.GUID, bg.oldApp.GUID); err != nil {
+				c.logger.UI.Warn("Unable to map route '%s' to app '%s': %s", r.URL(), bg.name, err.Error())
+			}
+		}
+		if bg.oldRenamed {
+			name := bg.name
+			if _, err := c.destCCSession.Applications().Update(bg.oldApp.GUID, models.AppParams{Name: &name}); err != nil {
+				c.logger.UI.Warn("Unable to rename app '%s' back to '%s': %s", bg.oldName, bg.name, err.Error())
+			}
+		}
+	}
+	c.blueGreenApps = nil
+}
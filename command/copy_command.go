@@ -1,8 +1,14 @@
 package command
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
+	cferrors "code.cloudfoundry.org/cli/cf/errors"
 	"code.cloudfoundry.org/cli/cf/models"
 	"code.cloudfoundry.org/cli/cf/terminal"
 	"code.cloudfoundry.org/cli/plugin"
@@ -24,6 +30,17 @@ type CopyCommand struct {
 	am copy.ApplicationsManager
 	sm copy.ServicesManager
 
+	srcApps []models.Application
+
+	// The source applications collected for the destination of the
+	// copy. They are collected again with the sessions of each other
+	// destination and for a different selection of them.
+	ac    copy.ApplicationCollection
+	acKey string
+
+	// The application content downloaded once for all destinations
+	downloads *helpers.DownloadCache
+
 	srcOrg    models.OrganizationFields
 	srcSpace  models.SpaceFields
 	destOrg   models.OrganizationFields
@@ -31,6 +48,25 @@ type CopyCommand struct {
 
 	srcCCSession  cfapi.CfSession
 	destCCSession cfapi.CfSession
+
+	conflicts     []conflict
+	blueGreenApps []blueGreenApp
+	appSteps      map[string]*appSteps
+
+	cache    *helpers.BitsCache
+	retry    *helpers.RetryPolicy
+	progress *helpers.Progress
+
+	startGate     *helpers.StartGate
+	serviceFilter *helpers.ServiceFilter
+
+	// The token refreshers of the target configs which are shared
+	// with the copies created with NewCopy
+	refreshers *tokenRefreshers
+
+	cliSession cfapi.CfSession
+	cliOrg     models.OrganizationFields
+	cliSpace   models.SpaceFields
 }
 
 // CopyOptions -
@@ -39,11 +75,32 @@ type CopyOptions struct {
 	DestOrg    string
 	DestTarget string
 
+	// Destinations - The destinations the source is copied to one
+	// after the other. The Dest* options are ignored if given.
+	Destinations []Destination
+
+	SourceSpace  string
+	SourceOrg    string
+	SourceTarget string
+
 	SourceAppNames []string
 	AppHostFormat  string
 	AppRouteDomain string
 
-	CopyAsDroplet bool
+	CopyAsDroplet  bool
+	StreamDroplets bool
+	CacheDir       string
+	CacheSize      int64
+
+	AppEnv   *helpers.AppEnv
+	AppScale *helpers.AppScale
+
+	NoStart        bool
+	StartOrderAuto bool
+	StartTiers     [][]string
+
+	Wait    bool
+	Timeout time.Duration
 
 	ServiceInstancesToCopyAsUPS []string
 	ServiceTypesToCopyAsUPS     []string
@@ -51,6 +108,36 @@ type CopyOptions struct {
 	RecreateServices bool
 	ServicesOnly     bool
 
+	AppConflict     string
+	ServiceConflict string
+
+	BlueGreen bool
+	KeepOld   bool
+	Strategy  string
+
+	Retries int
+
+	Interactive bool
+
+	Move         bool
+	DeleteSource bool
+	RemapRoutes  bool
+
+	Promotion *Promotion
+	OrgCopy   *OrgCopy
+
+	// Status - Report the copies in the current space that are out
+	// of date with their source instead of copying
+	Status bool
+
+	Force                 bool
+	AllowProtected        bool
+	ProtectedDestinations []helpers.ProtectedDestination
+
+	// Parallel - The copy runs at the same time as other copies
+	// so its progress is not redrawn in place
+	Parallel bool
+
 	Debug     bool
 	TracePath string
 }
@@ -74,25 +161,59 @@ func NewCopyCommand(
 		targets:         targets,
 		am:              applicationsManager,
 		sm:              servicesManager,
+		refreshers:      &tokenRefreshers{refreshers: make(map[string]*helpers.TokenRefresher)},
 	}
 }
 
-// Execute -
-func (c *CopyCommand) Execute(cli plugin.CliConnection, o *CopyOptions) {
+// NewCopy - Returns a copy command that runs independently of this one.
+// Commands that run at the same time cannot share the state of a copy
+// or the managers which are initialized for the copy they run. They do
+// share the token refreshers as the tokens of a target config must only
+// be refreshed and saved by one refresher.
+func (c *CopyCommand) NewCopy() CopyCmd {
+	return &CopyCommand{
+		sessionProvider: c.sessionProvider,
+		targets:         helpers.NewTargetsPluginInfo(),
+		am:              copy.NewCfCliApplicationsManager(),
+		sm:              copy.NewCfCliServicesManager(),
+		refreshers:      c.refreshers,
+	}
+}
 
-	defer c.cleanup()
+// Execute - Runs the copy and returns one of the Exit* codes
+func (c *CopyCommand) Execute(cli plugin.CliConnection, o *CopyOptions) int {
 
-	var (
-		ok  bool
-		err error
-	)
+	defer c.closeManagers()
 
 	c.logger = cfapi.NewLogger(o.Debug, o.TracePath)
-
 	c.cli = cli
+	c.ac = nil
+
+	if o.Status {
+		return c.reportCopyStatus(o)
+	}
+	if o.OrgCopy != nil {
+		return c.copyOrg(o)
+	}
+	if len(o.Destinations) > 0 {
+		return c.fanOut(o)
+	}
+	return c.copy(o)
+}
+
+// copy - Copies the source space to the destination of the given options
+func (c *CopyCommand) copy(o *CopyOptions) int {
+
+	defer c.closeSessions()
+
+	var (
+		exitCode int
+		err      error
+	)
+
 	c.o = o
 
-	if ok, err = c.initialize(); ok {
+	if exitCode, err = c.initialize(); exitCode == ExitOK {
 
 		var (
 			err     error
@@ -102,12 +223,11 @@ func (c *CopyCommand) Execute(cli plugin.CliConnection, o *CopyOptions) {
 			sc copy.ServiceCollection
 		)
 
-		currentTarget, _ := c.targets.GetCurrentTarget()
-		if currentTarget != o.DestTarget {
+		if o.SourceTarget != o.DestTarget {
 			message = fmt.Sprintf("Copying artifacts from %s %s / %s %s / %s %s to %s %s / %s %s / %s %s",
-				terminal.HeaderColor("target"), terminal.EntityNameColor(currentTarget),
+				terminal.HeaderColor("target"), terminal.EntityNameColor(o.SourceTarget),
 				terminal.HeaderColor("org"), terminal.EntityNameColor(c.srcOrg.Name),
-				terminal.HeaderColor("space"), terminal.EntityNameColor(c.srcCCSession.GetSessionSpace().Name),
+				terminal.HeaderColor("space"), terminal.EntityNameColor(c.srcSpace.Name),
 				terminal.HeaderColor("target"), terminal.EntityNameColor(c.o.DestTarget),
 				terminal.HeaderColor("org"), terminal.EntityNameColor(c.o.DestOrg),
 				terminal.HeaderColor("space"), terminal.EntityNameColor(c.o.DestSpace))
@@ -125,70 +245,230 @@ func (c *CopyCommand) Execute(cli plugin.CliConnection, o *CopyOptions) {
 		message += fmt.Sprintf(" as %s...", terminal.EntityNameColor(c.destCCSession.GetSessionUsername()))
 		c.logger.UI.Say(message)
 
-		if currentTarget == o.DestTarget {
-			// Restore source target on method exit. This needs
-			// to be done when the source and destination targets
-			// are the same. Otherwise the CLI session target
-			// will be set to the destination target on exit.
-			defer c.srcCCSession.SetSessionOrg(c.srcOrg)
-			defer c.srcCCSession.SetSessionSpace(c.srcSpace)
+		err = c.am.Init(c.srcCCSession, c.destCCSession, c.logger)
+		if err != nil {
+			c.logger.UI.Failed(err.Error())
+			return ExitTargetError
 		}
 
-		err = c.am.Init(c.srcCCSession, c.destCCSession, c.logger)
+		serviceKeyFormat := "__%s_copy_for_" + fmt.Sprintf("/%s/%s/%s", c.o.DestTarget, c.o.DestOrg, c.o.DestSpace)
+		err = c.sm.Init(helpers.NewServiceFilterSession(c.srcCCSession, c.serviceFilter), c.destCCSession, serviceKeyFormat, c.logger)
 		if err != nil {
 			c.logger.UI.Failed(err.Error())
-			return
+			return ExitTargetError
 		}
 
-		serviceKeyFormat := "__%s_copy_for_" + fmt.Sprintf("/%s/%s/%s", "destTarget", "destOrg", "destSpace")
-		err = c.sm.Init(c.srcCCSession, c.destCCSession, serviceKeyFormat, c.logger)
+		c.destCCSession.SetSessionOrg(c.destOrg)
+		c.destCCSession.SetSessionSpace(c.destSpace)
+
+		err = c.findConflicts()
 		if err != nil {
 			c.logger.UI.Failed(err.Error())
-			return
+			return ExitPreflightFailure
+		}
+		confirmed, err := c.confirmCopy()
+		if err != nil {
+			c.logger.UI.Failed(err.Error())
+			return ExitNotConfirmed
+		}
+		if !confirmed {
+			c.logger.UI.Say("Copy cancelled.")
+			return ExitNotConfirmed
+		}
+		copyApps := !o.ServicesOnly && len(o.SourceAppNames) > 0
+		c.appSteps = nil
+		if copyApps {
+			c.trackAppSteps()
 		}
 
-		if !o.ServicesOnly {
-			ac, err = c.am.ApplicationsToBeCopied(o.SourceAppNames, o.CopyAsDroplet)
-			if err != nil {
-				c.logger.UI.Failed(err.Error())
-				return
+		// The source apps and services are collected before the conflicts
+		// are resolved so nothing is deleted or renamed at the destination
+		// until the artifacts to replace them are in hand
+		if copyApps && !c.copiesDropletsDirectly() {
+			// The collection belongs to the sessions the applications
+			// manager is initialized with for this destination. When the
+			// applications are copied to more than one destination their
+			// content is only downloaded once as the downloads are cached.
+			acKey := fmt.Sprintf("%s/%s/%s|%v|%t", o.DestTarget, o.DestOrg, o.DestSpace, o.SourceAppNames, o.CopyAsDroplet)
+			if ac = c.ac; ac == nil || c.acKey != acKey {
+				ac, err = c.am.ApplicationsToBeCopied(o.SourceAppNames, o.CopyAsDroplet)
+				if err != nil {
+					c.logger.UI.Failed(err.Error())
+					return ExitPreflightFailure
+				}
+				c.ac, c.acKey = ac, acKey
 			}
 		}
 
+		// The services are collected for each destination as the
+		// service keys they are copied with are named after the
+		// destination and the services skipped at the destination
+		// are hidden from the services manager
 		sc, err = c.sm.ServicesToBeCopied(o.SourceAppNames, o.ServiceInstancesToCopyAsUPS, o.ServiceTypesToCopyAsUPS)
 		if err != nil {
 			c.logger.UI.Failed(err.Error())
-			return
+			return ExitPreflightFailure
 		}
 
-		c.destCCSession.SetSessionOrg(c.destOrg)
-		c.destCCSession.SetSessionSpace(c.destSpace)
+		err = c.resolveConflicts()
+		if err != nil {
+			c.logger.UI.Failed(err.Error())
+			return ExitPreflightFailure
+		}
+		if copyApps {
+			defer c.rollbackBlueGreen()
+			err = c.prepareBlueGreen()
+			if err != nil {
+				c.logger.UI.Failed(err.Error())
+				return ExitPreflightFailure
+			}
+		}
 
+		destServices, err := c.destServiceNames()
+		if err != nil {
+			c.logger.UI.Failed(err.Error())
+			return ExitPreflightFailure
+		}
 		err = c.sm.DoCopy(sc, o.RecreateServices)
 		if err != nil {
 			c.logger.UI.Failed(err.Error())
-			return
+			if c.destinationChanged(destServices) {
+				return ExitPartialCopy
+			}
+			return ExitTotalFailure
+		}
+
+		if copyApps {
+			var started []string
+
+			if c.copiesDropletsDirectly() {
+				err = c.copyApplicationsDirectly()
+			} else {
+				if err = c.am.DoCopy(ac, sc, o.AppHostFormat, o.AppRouteDomain); err == nil {
+					err = c.bindKeptServices()
+				}
+			}
+			if err != nil {
+				c.logger.UI.Failed(err.Error())
+				return ExitPartialCopy
+			}
+			started, err = c.startDestApplications()
+			if err != nil {
+				c.logger.UI.Failed(err.Error())
+				return ExitPartialCopy
+			}
+			err = c.completeBlueGreen()
+			if err != nil {
+				c.logger.UI.Failed(err.Error())
+				return ExitPartialCopy
+			}
+			err = c.completeRolling()
+			if err != nil {
+				c.logger.UI.Failed(err.Error())
+				return ExitPartialCopy
+			}
+			if o.Wait {
+				healthy, err := c.reportApplicationHealth(started)
+				if err != nil {
+					c.logger.UI.Failed(err.Error())
+					return ExitPartialCopy
+				}
+				if !healthy {
+					c.logger.UI.Failed("Not all copied apps are healthy.")
+					return ExitPartialCopy
+				}
+			}
 		}
 
-		if !o.ServicesOnly {
-			err = c.am.DoCopy(ac, sc, o.AppHostFormat, o.AppRouteDomain)
+		if err = c.annotateCopies(); err != nil {
+			c.logger.UI.Warn("The sources of the copies could not be recorded: %s", err.Error())
+		}
+		if copyApps && o.Move {
+			err = c.decommissionSource()
 			if err != nil {
 				c.logger.UI.Failed(err.Error())
-				return
+				return ExitPartialCopy
+			}
+		}
+
+		if o.Promotion != nil {
+			if err = c.recordPromotion(); err != nil {
+				c.logger.UI.Warn("The promotion could not be recorded: %s", err.Error())
 			}
 		}
 
+		c.printConflicts("Apps and services that existed at the destination:", true)
+
 		c.logger.UI.Say("")
 		c.logger.UI.Ok()
+		return ExitOK
 	}
 	if err != nil {
 		c.logger.UI.Failed(err.Error())
 	}
+	return exitCode
+}
+
+// destServiceNames - Returns the names of the services that
+// exist in the destination space
+func (c *CopyCommand) destServiceNames() (names map[string]bool, err error) {
+
+	var services []models.ServiceInstance
+
+	if services, err = c.destCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	names = make(map[string]bool)
+	for _, s := range services {
+		names[s.Name] = true
+	}
+	return
+}
+
+// destinationChanged - Returns whether the destination was changed by
+// a copy that failed. This is the case if conflicting apps or services
+// were replaced or renamed or if services were created other than the
+// given services that existed before the services were copied.
+func (c *CopyCommand) destinationChanged(servicesBefore map[string]bool) bool {
+
+	for _, cf := range c.conflicts {
+		if cf.action == ConflictReplace || cf.action == ConflictRename {
+			return true
+		}
+	}
+	servicesAfter, err := c.destServiceNames()
+	if err != nil {
+		// Assume the worst if the destination cannot be checked
+		c.logger.DebugMessage("Unable to list destination services: %s", err.Error())
+		return true
+	}
+	for name := range servicesAfter {
+		if !servicesBefore[name] {
+			return true
+		}
+	}
+	return false
 }
 
-func (c *CopyCommand) cleanup() {
+// closeManagers - Releases the resources of the managers once
+// the copies to all destinations are done
+func (c *CopyCommand) closeManagers() {
 	c.am.Close()
 	c.sm.Close()
+}
+
+// closeSessions - Closes the sessions of a copy to one destination
+func (c *CopyCommand) closeSessions() {
+
+	if c.cliSession != nil {
+		// Restore the CLI target on exit. This needs to be
+		// done when the source or destination session shares
+		// the CLI's config and its org or space is changed.
+		// Otherwise the CLI session target will be set to the
+		// copy's source or destination on exit.
+		c.cliSession.SetSessionOrg(c.cliOrg)
+		c.cliSession.SetSessionSpace(c.cliSpace)
+	}
 
 	if c.srcCCSession != nil {
 		c.srcCCSession.Close()
@@ -196,126 +476,383 @@ func (c *CopyCommand) cleanup() {
 	if c.destCCSession != nil {
 		c.destCCSession.Close()
 	}
+	c.cliSession = nil
+	c.srcCCSession = nil
+	c.destCCSession = nil
 }
 
-func (c *CopyCommand) initialize() (ok bool, err error) {
+func (c *CopyCommand) initialize() (exitCode int, err error) {
 
 	var (
 		currentTarget string
-		output        []string
 
 		apps  []models.Application
 		org   models.Organization
 		space models.Space
 	)
 
-	if output, err = c.cli.CliCommandWithoutTerminalOutput("plugins"); err != nil {
+	exitCode = ExitTargetError
+
+	if currentTarget, err = c.resolveTargets(); err != nil {
 		return
 	}
-	for _, s := range output[4:] {
-		if len(s) > 0 && s[:10] == "cf-targets" {
-			ok = true
-			break
-		}
-	}
-	if !ok {
-		c.logger.UI.Failed("'Targets' plugin is requried to determine destination Cloud Foundry target.")
+
+	// Initialize and validate source and destination sessions
+	sslDisabled, _ := c.cli.IsSSLDisabled()
+
+	if c.srcCCSession, err = c.sessionProvider.NewCfSessionFromFilepath(
+		c.targets.GetTargetConfigPath(c.o.SourceTarget), sslDisabled, c.logger); err != nil {
+
+		c.logger.UI.Failed("Error creating source session: %s", err.Error())
 		return
 	}
+	if c.destCCSession, err = c.sessionProvider.NewCfSessionFromFilepath(
+		c.targets.GetTargetConfigPath(c.o.DestTarget), sslDisabled, c.logger); err != nil {
 
-	ok = false
-	if err = c.targets.Initialize(); err != nil {
+		c.logger.UI.Failed("Error creating destination session: %s", err.Error())
 		return
 	}
+	c.srcCCSession = c.newRefreshingSession(c.srcCCSession, c.o.SourceTarget, sslDisabled)
+	c.destCCSession = c.newRefreshingSession(c.destCCSession, c.o.DestTarget, sslDisabled)
 
-	if currentTarget, err = c.targets.GetCurrentTarget(); err == nil {
+	if c.o.Retries > 0 {
+		c.retry = helpers.NewRetryPolicy(c.o.Retries, c.logger.DebugMessage)
+		c.srcCCSession = helpers.NewRetrySession(c.srcCCSession, c.retry)
+		c.destCCSession = helpers.NewRetrySession(c.destCCSession, c.retry)
+	}
 
-		if c.o.DestTarget != "" {
-			if c.o.DestTarget != currentTarget && !c.targets.HasTarget(c.o.DestTarget) {
-				c.logger.UI.Failed("A target named '%s' cannot be found.", c.o.DestTarget)
-				return
-			}
+	// The content of the source applications is downloaded only once
+	// when they are copied to more than one destination
+	if c.downloads != nil {
+		c.srcCCSession = helpers.NewDownloadCacheSession(c.srcCCSession, c.downloads)
+	}
+
+	// Report the progress of the bits transferred by the sessions
+	// as the applications manager does not report it itself
+	c.progress = helpers.NewProgress(c.logger.UI, helpers.IsTerminal(os.Stdout) && !c.o.Parallel)
+	c.srcCCSession = helpers.NewProgressSession(c.srcCCSession, c.progress, c.srcTransferStarted)
+	c.destCCSession = helpers.NewProgressSession(c.destCCSession, c.progress, c.destTransferStarted)
+
+	// Apply the changes made by the copy to the copied applications
+	// when they are created at the destination and hold back their
+	// start until the copy starts them
+	c.startGate = helpers.NewStartGate()
+	c.destCCSession = helpers.NewAppCreateSession(c.destCCSession, c.prepareDestApplication, c.startGate)
+
+	// Services skipped on conflict are left out of the services copied
+	c.serviceFilter = helpers.NewServiceFilter()
+
+	if c.o.SourceOrg != "" || c.o.SourceSpace != "" {
+		if c.o.SourceTarget == currentTarget {
+			c.saveCLITarget(c.srcCCSession)
+		}
+		if err = c.setSourceOrgAndSpace(); err != nil {
+			return
+		}
+	} else if !c.srcCCSession.HasTarget() {
+		c.logger.UI.Failed("The CLI target org and space needs to be set.")
+		return
+	}
+	if c.o.DestTarget == currentTarget && c.cliSession == nil {
+		if c.o.SourceTarget == currentTarget {
+			c.saveCLITarget(c.srcCCSession)
 		} else {
-			c.o.DestTarget = currentTarget
+			c.saveCLITarget(c.destCCSession)
 		}
+	}
+
+	c.logger.DebugMessage("Options => %# v", c.o)
+	c.logger.DebugMessage("Source Org => %# v\n", c.srcCCSession.GetSessionOrg())
+	c.logger.DebugMessage("Source Space => %# v\n", c.srcCCSession.GetSessionSpace())
+
+	if c.o.DestOrg == "" {
+		c.o.DestOrg = c.srcCCSession.GetSessionOrg().Name
+	}
+	if c.o.SourceTarget == c.o.DestTarget &&
+		c.srcCCSession.GetSessionOrg().Name == c.o.DestOrg &&
+		c.srcCCSession.GetSessionSpace().Name == c.o.DestSpace {
 
-		// Initialize and validate source and destination sessions
-		sslDisabled, _ := c.cli.IsSSLDisabled()
+		c.logger.UI.Failed("The source and destination are the same.")
+		exitCode = ExitUsageError
+		return
+	}
+	if c.o.RemapRoutes && c.o.SourceTarget != c.o.DestTarget {
+		c.logger.UI.Failed("Routes can only be moved to apps on the same target.")
+		exitCode = ExitUsageError
+		return
+	}
 
-		if c.srcCCSession, err = c.sessionProvider.NewCfSessionFromFilepath(
-			c.targets.GetTargetConfigPath(currentTarget), sslDisabled, c.logger); err != nil {
+	exitCode = ExitPreflightFailure
 
-			c.logger.UI.Failed("Error creating source session: %s", err.Error())
+	if c.o.RemapRoutes {
+		if err = c.checkRouteSharing(); err != nil {
 			return
 		}
-		if c.destCCSession, err = c.sessionProvider.NewCfSessionFromFilepath(
-			c.targets.GetTargetConfigPath(c.o.DestTarget), sslDisabled, c.logger); err != nil {
+	}
 
-			c.logger.UI.Failed("Error creating destination session: %s", err.Error())
+	apps, err = c.srcCCSession.AppSummary().GetSummariesInCurrentSpace()
+	if err != nil {
+		return
+	}
+	c.srcApps = apps
+	if c.o.Interactive {
+		if err = c.selectInteractively(); err != nil {
+			c.logger.UI.Failed(err.Error())
 			return
 		}
+	} else if len(c.o.SourceAppNames) == 0 {
+		// Retrieve all application names to be copied
+		for _, a := range apps {
+			c.o.SourceAppNames = append(c.o.SourceAppNames, a.ApplicationFields.Name)
+		}
+	} else if err = c.validateSourceSelection(apps); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return
+	}
 
-		if !c.srcCCSession.HasTarget() {
-			c.logger.UI.Failed("The CLI target org and space needs to be set.")
+	// Retrieve source and destination org and space
+	c.srcOrg = c.srcCCSession.GetSessionOrg()
+	c.srcSpace = c.srcCCSession.GetSessionSpace()
+
+	org, err = c.destCCSession.Organizations().FindByName(c.o.DestOrg)
+	if err != nil {
+		exitCode = destinationExitCode(err)
+		return
+	}
+	c.destOrg = org.OrganizationFields
+
+	space, err = c.destCCSession.Spaces().FindByNameInOrg(c.o.DestSpace, c.destOrg.GUID)
+	if err != nil {
+		exitCode = destinationExitCode(err)
+		return
+	}
+	c.destSpace = space.SpaceFields
+
+	if err = c.checkProtectedDestination(); err != nil {
+		return
+	}
+	if !c.o.ServicesOnly {
+		if err = c.checkDestQuota(org, space); err != nil {
+			return
+		}
+	}
+	if c.o.CacheDir != "" {
+		if c.o.SourceTarget == c.o.DestTarget {
+			c.logger.UI.Warn("Droplets copied within a target are not cached as they are copied by the Cloud Controller.")
+		} else if c.cache, err = helpers.NewBitsCache(c.o.CacheDir, c.o.CacheSize); err != nil {
 			return
 		}
+	}
+
+	c.logger.DebugMessage("Destination Org => %# v", c.destOrg)
+	c.logger.DebugMessage("Destination Space => %# v", c.destSpace)
+
+	exitCode = ExitOK
+	return
+}
+
+// destinationExitCode - Returns the exit code for an error looking up
+// the destination org or space. Only a destination that was not found
+// is reported as such as other errors come from the target.
+func destinationExitCode(err error) int {
+	if _, ok := err.(*cferrors.ModelNotFoundError); ok {
+		return ExitDestinationNotFound
+	}
+	return ExitTargetError
+}
 
-		c.logger.DebugMessage("Options => %# v", c.o)
-		c.logger.DebugMessage("Source Org => %# v\n", c.srcCCSession.GetSessionOrg())
-		c.logger.DebugMessage("Source Space => %# v\n", c.srcCCSession.GetSessionSpace())
+// resolveTargets - Validates that the source and destination targets
+// exist and defaults them to the current target of the CLI which is
+// returned
+func (c *CopyCommand) resolveTargets() (currentTarget string, err error) {
 
-		if c.o.DestOrg == "" {
-			c.o.DestOrg = c.srcCCSession.GetSessionOrg().Name
+	var (
+		found  bool
+		output []string
+	)
+
+	if output, err = c.cli.CliCommandWithoutTerminalOutput("plugins"); err != nil {
+		return
+	}
+	for _, s := range output[4:] {
+		if len(s) > 0 && s[:10] == "cf-targets" {
+			found = true
+			break
 		}
-		if currentTarget == c.o.DestTarget &&
-			c.srcCCSession.GetSessionOrg().Name == c.o.DestOrg &&
-			c.srcCCSession.GetSessionSpace().Name == c.o.DestSpace {
+	}
+	if !found {
+		err = errors.New("'Targets' plugin is requried to determine destination Cloud Foundry target.")
+		return
+	}
 
-			c.logger.UI.Failed("The source and destination are the same.")
+	if err = c.targets.Initialize(); err != nil {
+		return
+	}
+	if currentTarget, err = c.targets.GetCurrentTarget(); err != nil {
+		return
+	}
+
+	if c.o.SourceTarget != "" {
+		if c.o.SourceTarget != currentTarget && !c.targets.HasTarget(c.o.SourceTarget) {
+			err = fmt.Errorf("A target named '%s' cannot be found.", c.o.SourceTarget)
+			return
+		}
+	} else {
+		c.o.SourceTarget = currentTarget
+	}
+	if c.o.DestTarget != "" {
+		if c.o.DestTarget != currentTarget && !c.targets.HasTarget(c.o.DestTarget) {
+			err = fmt.Errorf("A target named '%s' cannot be found.", c.o.DestTarget)
 			return
 		}
+	} else {
+		c.o.DestTarget = currentTarget
+	}
+	return
+}
 
-		apps, err = c.srcCCSession.AppSummary().GetSummariesInCurrentSpace()
-		if err != nil {
+// validateSourceSelection - Validates that the selected applications
+// exist and that the services to be copied as user provided services are
+// bound to them. All problems found are reported together.
+func (c *CopyCommand) validateSourceSelection(apps []models.Application) (err error) {
+
+	var services []models.ServiceInstance
+
+	problems := []string{}
+	for _, n := range c.o.SourceAppNames {
+		if _, contains := utils.ContainsApp(n, apps); !contains {
+			problems = append(problems, fmt.Sprintf("The application '%s' does not exist.", n))
+		}
+	}
+
+	if len(c.o.ServiceInstancesToCopyAsUPS) > 0 {
+		if services, err = c.srcCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
 			return
 		}
-		if len(c.o.SourceAppNames) == 0 {
-			// Retrieve all application names to be copied
-			for _, a := range apps {
-				c.o.SourceAppNames = append(c.o.SourceAppNames, a.ApplicationFields.Name)
-			}
-		} else {
-			// Validate source application exists
-			for _, n := range c.o.SourceAppNames {
-				if _, contains := utils.ContainsApp(n, apps); !contains {
-					if err != nil {
-						c.logger.UI.Failed("The application '%s' does not exist.", n)
-						return
+		for _, name := range c.o.ServiceInstancesToCopyAsUPS {
+			found, bound := false, false
+			for _, s := range services {
+				if s.Name == name {
+					found = true
+					for _, appName := range c.o.SourceAppNames {
+						bound = bound || containsString(s.ApplicationNames, appName)
 					}
+					break
 				}
 			}
+			switch {
+			case !found:
+				problems = append(problems, fmt.Sprintf("The service '%s' to copy as a user provided service does not exist.", name))
+			case !bound:
+				problems = append(problems, fmt.Sprintf("The service '%s' to copy as a user provided service is not bound to any of the apps being copied.", name))
+			}
 		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
 
-		// Retrieve source and destination org and space
-		c.srcOrg = c.srcCCSession.GetSessionOrg()
-		c.srcSpace = c.srcCCSession.GetSessionSpace()
+// saveCLITarget - Saves the org and space of the given session which
+// shares the CLI's config so it can be restored once the copy is done
+func (c *CopyCommand) saveCLITarget(session cfapi.CfSession) {
+	c.cliSession = session
+	c.cliOrg = session.GetSessionOrg()
+	c.cliSpace = session.GetSessionSpace()
+}
 
-		org, err = c.destCCSession.Organizations().FindByName(c.o.DestOrg)
-		if err != nil {
-			return
+// setSourceOrgAndSpace - Targets the source session at the org and
+// space given in the copy options. If only a space is given it is
+// looked up in the org the source session is targeted at.
+func (c *CopyCommand) setSourceOrgAndSpace() (err error) {
+
+	var (
+		org   models.Organization
+		space models.Space
+	)
+
+	if c.o.SourceOrg == "" {
+		if !c.srcCCSession.HasTarget() {
+			return fmt.Errorf("The source target '%s' has no org targeted so a source org must be provided.", c.o.SourceTarget)
 		}
-		c.destOrg = org.OrganizationFields
+		c.o.SourceOrg = c.srcCCSession.GetSessionOrg().Name
+	}
 
-		space, err = c.destCCSession.Spaces().FindByNameInOrg(c.o.DestSpace, c.destOrg.GUID)
-		if err != nil {
+	if org, err = c.srcCCSession.Organizations().FindByName(c.o.SourceOrg); err != nil {
+		return
+	}
+	if space, err = c.srcCCSession.Spaces().FindByNameInOrg(c.o.SourceSpace, org.GUID); err != nil {
+		return
+	}
+	c.srcCCSession.SetSessionOrg(org.OrganizationFields)
+	c.srcCCSession.SetSessionSpace(space.SpaceFields)
+	return
+}
+
+// newCCClient - Creates a Cloud Controller client for the given
+// target which retries requests using the copy's retry policy
+func (c *CopyCommand) newCCClient(target string) (ccClient *helpers.CCClient, err error) {
+	if ccClient, err = helpers.NewCCClientFromFilepath(c.targets.GetTargetConfigPath(target)); err != nil {
+		return
+	}
+	ccClient.SetRetryPolicy(c.retry)
+	if refresher, ok := c.refreshers.find(c.targets.GetTargetConfigPath(target)); ok {
+		ccClient.SetTokenRefresher(refresher)
+	}
+	return
+}
+
+// newRefreshingSession - Wraps the given session of the given target so
+// that its access token is refreshed before it expires. All sessions of
+// the same target share the refresher of the target's config file.
+// Tokens of targets whose config does not have a refresh token are not
+// refreshed.
+func (c *CopyCommand) newRefreshingSession(session cfapi.CfSession, target string, sslDisabled bool) cfapi.CfSession {
+
+	configPath := c.targets.GetTargetConfigPath(target)
+
+	refresher, err := c.refreshers.get(configPath)
+	if err != nil {
+		c.logger.DebugMessage("Access token of target '%s' will not be refreshed: %s", target, err.Error())
+		return session
+	}
+
+	return helpers.NewRefreshingSession(session, refresher, func() (cfapi.CfSession, error) {
+		return c.sessionProvider.NewCfSessionFromFilepath(configPath, sslDisabled, c.logger)
+	}, c.logger.DebugMessage)
+}
+
+// tokenRefreshers - The refreshers of the tokens saved in target configs
+// keyed by the path of the config. They are shared by the copies which
+// run at the same time.
+type tokenRefreshers struct {
+	refreshers map[string]*helpers.TokenRefresher
+	mutex      sync.Mutex
+}
+
+// get - Returns the refresher of the given config and creates it if
+// the config does not have one yet
+func (r *tokenRefreshers) get(configPath string) (refresher *helpers.TokenRefresher, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var ok bool
+	if refresher, ok = r.refreshers[configPath]; !ok {
+		if refresher, err = helpers.NewTokenRefresher(configPath); err != nil {
 			return
 		}
-		c.destSpace = space.SpaceFields
+		r.refreshers[configPath] = refresher
+	}
+	return
+}
 
-		c.logger.DebugMessage("Destination Org => %# v", c.destOrg)
-		c.logger.DebugMessage("Destination Space => %# v", c.destSpace)
+// find - Returns the refresher of the given config if it has one
+func (r *tokenRefreshers) find(configPath string) (refresher *helpers.TokenRefresher, ok bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
 
-		ok = true
-	}
+	refresher, ok = r.refreshers[configPath]
 	return
 }
@@ -1,19 +1,37 @@
 package command_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"code.cloudfoundry.org/cli/cf/api"
+	"code.cloudfoundry.org/cli/cf/api/apifakes"
+	"code.cloudfoundry.org/cli/cf/api/appinstances"
+	"code.cloudfoundry.org/cli/cf/api/appinstances/appinstancesfakes"
+	"code.cloudfoundry.org/cli/cf/api/applications"
+	"code.cloudfoundry.org/cli/cf/api/applications/applicationsfakes"
 	"code.cloudfoundry.org/cli/cf/api/organizations"
 	"code.cloudfoundry.org/cli/cf/api/spaces"
+	cferrors "code.cloudfoundry.org/cli/cf/errors"
 	"code.cloudfoundry.org/cli/cf/models"
 	. "code.cloudfoundry.org/cli/plugin/pluginfakes"
 	io_helpers "code.cloudfoundry.org/cli/util/testhelpers/io"
 	"github.com/mevansam/cf-cli-api/cfapi"
 	. "github.com/mevansam/cf-cli-api/cfapi/mocks"
+	"github.com/mevansam/cf-cli-api/copy"
 	. "github.com/mevansam/cf-cli-api/copy/mocks"
 	. "github.com/mevansam/cf-copy-plugin/command"
 	. "github.com/mevansam/cf-copy-plugin/command/mocks"
+	"github.com/mevansam/cf-copy-plugin/helpers"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -29,16 +47,65 @@ var _ = Describe("Copy Command Tests", func() {
 		mockApplicationsManager *MockApplicationsManager
 		mockServicesManager     *MockServicesManager
 		copyCommand             CopyCmd
+
+		// A stub of the Cloud Controller API of both targets
+		// which records the requests made to it
+		configDir   string
+		ccServer    *httptest.Server
+		ccRequests  []string
+		ccMutex     sync.Mutex
+		ccResponses map[string]string
+		ccStatus    map[string]int
+		// Responds to the requests it returns true for
+		ccHandler func(w http.ResponseWriter, r *http.Request) bool
 	)
 
+	writeConfig := func(name string) string {
+		configPath := filepath.Join(configDir, name)
+		data, _ := json.Marshal(map[string]interface{}{
+			"Target":      ccServer.URL,
+			"AccessToken": "bearer fake_token",
+		})
+		Expect(ioutil.WriteFile(configPath, data, 0600)).To(Succeed())
+		return configPath
+	}
+
 	BeforeEach(func() {
 		fakeCliConnection = &FakeCliConnection{}
 
+		ccRequests = []string{}
+		ccResponses = make(map[string]string)
+		ccStatus = make(map[string]int)
+		ccHandler = nil
+		ccServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			// Requests are handled on the server's goroutines
+			ccMutex.Lock()
+			ccRequests = append(ccRequests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+			ccMutex.Unlock()
+			if ccHandler != nil && ccHandler(w, r) {
+				return
+			}
+			if status, ok := ccStatus[r.Method+" "+r.URL.Path]; ok {
+				w.WriteHeader(status)
+				fmt.Fprint(w, "{}")
+				return
+			}
+			if response, ok := ccResponses[r.Method+" "+r.URL.Path]; ok {
+				fmt.Fprint(w, response)
+				return
+			}
+			fmt.Fprint(w, "{}")
+		}))
+		configDir, _ = ioutil.TempDir("", "copy-command")
+		srcConfig := writeConfig("source.json")
+		destConfig := writeConfig("dest.json")
+
 		mockTargets = &MockTargets{
 			CurrentTarget: "fake_source_target",
 			Targets: map[string]string{
-				"fake_source_target": "/fake/source/target.json",
-				"fake_dest_target":   "/fake/dest/target.json",
+				"fake_source_target": srcConfig,
+				"fake_dest_target":   destConfig,
 			},
 		}
 
@@ -46,14 +113,19 @@ var _ = Describe("Copy Command Tests", func() {
 		mockDestSession = &MockSession{}
 
 		mockSessionProvider = &MockSessionProvider{MockSessionMap: make(map[string]cfapi.CfSession)}
-		mockSessionProvider.MockSessionMap["/fake/source/target.json"] = mockSrcSession
-		mockSessionProvider.MockSessionMap["/fake/dest/target.json"] = mockDestSession
+		mockSessionProvider.MockSessionMap[srcConfig] = mockSrcSession
+		mockSessionProvider.MockSessionMap[destConfig] = mockDestSession
 
 		mockApplicationsManager = &MockApplicationsManager{}
 		mockServicesManager = &MockServicesManager{}
 		copyCommand = NewCopyCommand(mockTargets, mockSessionProvider, mockApplicationsManager, mockServicesManager)
 	})
 
+	AfterEach(func() {
+		ccServer.Close()
+		os.RemoveAll(configDir)
+	})
+
 	Context("Test initialization", func() {
 
 		It("Recognizes cf-targets plugin has not been installed", func() {
@@ -70,8 +142,9 @@ var _ = Describe("Copy Command Tests", func() {
 			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
 				return strings.Split(cf_plugins_out_2, "\n"), nil
 			}
+			var exitCode int
 			output := io_helpers.CaptureOutput(func() {
-				copyCommand.Execute(fakeCliConnection, &CopyOptions{
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
 					DestSpace:      "fake_dest_space",
 					DestOrg:        "fake_dest_org",
 					DestTarget:     "fake_unknown_dest_target",
@@ -80,6 +153,24 @@ var _ = Describe("Copy Command Tests", func() {
 			})
 			Expect(output[0]).To(Equal("FAILED"))
 			Expect(output[1]).To(Equal("A target named 'fake_unknown_dest_target' cannot be found."))
+			Expect(exitCode).To(Equal(ExitTargetError))
+		})
+		It("Recognizes that the given source target does not exist", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				return strings.Split(cf_plugins_out_2, "\n"), nil
+			}
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:    "fake_dest_space",
+					DestTarget:   "fake_dest_target",
+					SourceSpace:  "fake_src_space",
+					SourceTarget: "fake_unknown_src_target",
+				})
+			})
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("A target named 'fake_unknown_src_target' cannot be found."))
+			Expect(exitCode).To(Equal(ExitTargetError))
 		})
 		It("Recognizes that the current session does not have a target", func() {
 			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
@@ -104,8 +195,9 @@ var _ = Describe("Copy Command Tests", func() {
 			mockSrcSession.MockHasTarget = func() bool { return true }
 			mockSrcSession.MockGetSessionOrg = func() models.OrganizationFields { return models.OrganizationFields{Name: "fake_dest_org"} }
 			mockSrcSession.MockGetSessionSpace = func() models.SpaceFields { return models.SpaceFields{Name: "fake_dest_space"} }
+			var exitCode int
 			output := io_helpers.CaptureOutput(func() {
-				copyCommand.Execute(fakeCliConnection, &CopyOptions{
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
 					DestSpace:      "fake_dest_space",
 					DestOrg:        "fake_dest_org",
 					SourceAppNames: []string{"fake_source_app"},
@@ -113,6 +205,30 @@ var _ = Describe("Copy Command Tests", func() {
 			})
 			Expect(output[0]).To(Equal("FAILED"))
 			Expect(output[1]).To(Equal("The source and destination are the same."))
+			Expect(exitCode).To(Equal(ExitUsageError))
+		})
+		It("Reports the result of the copy to each destination", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				return strings.Split(cf_plugins_out_2, "\n"), nil
+			}
+			mockSrcSession.MockHasTarget = func() bool { return true }
+			mockSrcSession.MockGetSessionOrg = func() models.OrganizationFields { return models.OrganizationFields{Name: "fake_dest_org"} }
+			mockSrcSession.MockGetSessionSpace = func() models.SpaceFields { return models.SpaceFields{Name: "fake_dest_space"} }
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					Destinations: []Destination{
+						{Org: "fake_dest_org", Space: "fake_dest_space"},
+						{Target: "fake_unknown_dest_target", Org: "fake_dest_org", Space: "fake_dest_space"},
+					},
+					SourceAppNames: []string{"fake_source_app"},
+				})
+			})
+			Expect(output).To(ContainElement("The source and destination are the same."))
+			Expect(output).To(ContainElement("A target named 'fake_unknown_dest_target' cannot be found."))
+			Expect(output).To(ContainElement(ContainSubstring("fake_unknown_dest_target/fake_dest_org/fake_dest_space")))
+			Expect(output).To(ContainElement("All 2 copies to the destinations failed."))
+			Expect(exitCode).To(Equal(ExitTotalFailure))
 		})
 	})
 
@@ -134,7 +250,16 @@ var _ = Describe("Copy Command Tests", func() {
 					},
 				}
 			}
+			mockSrcSession.MockServiceSummary = func() api.ServiceSummaryRepository {
+				return &apifakes.FakeServiceSummaryRepository{}
+			}
 			mockDestSession.MockGetSessionUsername = func() string { return "fake_user" }
+			mockDestSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{}
+			}
+			mockDestSession.MockServiceSummary = func() api.ServiceSummaryRepository {
+				return &apifakes.FakeServiceSummaryRepository{}
+			}
 			mockDestSession.MockOrganizations = func() organizations.OrganizationRepository {
 				return &FakeOrganizationRepository{
 					FindByNameStub: func(name string) (org models.Organization, apiErr error) {
@@ -166,19 +291,1260 @@ var _ = Describe("Copy Command Tests", func() {
 		})
 
 		It("Should set the target org and space", func() {
+			var exitCode int
 			output := io_helpers.CaptureOutput(func() {
-				copyCommand.Execute(fakeCliConnection, &CopyOptions{
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
 					DestSpace:      "fake_dest_space",
 					DestOrg:        "fake_dest_org",
 					DestTarget:     "fake_dest_target",
 					SourceAppNames: []string{"fake_source_app"},
+					Force:          true,
 				})
 			})
 			Expect(output[2]).To(Equal("OK"))
+			Expect(exitCode).To(Equal(ExitOK))
+		})
+
+		It("Should report the steps of apps copied by the applications manager", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}}
+						apps[0].Name = "fake_source_app"
+						apps[0].State = "started"
+						return
+					},
+				}
+			}
+			fakeDestApplications := &applicationsfakes.FakeRepository{}
+			mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app"},
+					Force:          true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+			Expect(output).To(ContainElement(MatchRegexp(`^\[3/3\] .*fake_source_app.*: start\.\.\.$`)))
+			Expect(fakeDestApplications.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("Should copy only the apps selected interactively", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}, models.Application{}}
+						apps[0].Name = "fake_source_app"
+						apps[0].State = "started"
+						apps[1].Name = "fake_app1"
+						apps[1].State = "started"
+						return
+					},
+				}
+			}
+			fakeDestApplications := &applicationsfakes.FakeRepository{}
+			mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+
+			stdin := os.Stdin
+			isTerminal := helpers.IsTerminal
+			defer func() {
+				os.Stdin = stdin
+				helpers.IsTerminal = isTerminal
+			}()
+			r, w, _ := os.Pipe()
+			fmt.Fprint(w, "2\n")
+			w.Close()
+			os.Stdin = r
+			helpers.IsTerminal = func(f *os.File) bool { return f == os.Stdin }
+
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:   "fake_dest_space",
+					DestOrg:     "fake_dest_org",
+					DestTarget:  "fake_dest_target",
+					Interactive: true,
+					Force:       true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+			Expect(output).To(ContainElement(MatchRegexp(`^\[3/3\] .*fake_app1.*: start\.\.\.$`)))
+			Expect(output).ToNot(ContainElement(MatchRegexp(`fake_source_app.*: start`)))
+			Expect(fakeDestApplications.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("Should record the droplets of the promoted apps", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}}
+						apps[0].GUID = "fake_src_app_guid"
+						apps[0].Name = "fake_source_app"
+						return
+					},
+				}
+			}
+			ccResponses["GET /v3/apps/fake_src_app_guid/droplets/current"] =
+				`{"guid":"fake_droplet_guid","checksum":{"type":"sha256","value":"fake_checksum"}}`
+			historyPath := filepath.Join(configDir, helpers.PromotionHistoryFile)
+
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app"},
+					Force:          true,
+					Promotion: &Promotion{
+						Chain:       "release",
+						From:        "dev",
+						To:          "prod",
+						HistoryPath: historyPath,
+					},
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+			Expect(output).To(ContainElement(ContainSubstring("Recorded promotion of 1 apps")))
+
+			data, err := ioutil.ReadFile(historyPath)
+			Expect(err).ToNot(HaveOccurred())
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			Expect(lines).To(HaveLen(1))
+
+			var record helpers.PromotionRecord
+			Expect(json.Unmarshal([]byte(lines[0]), &record)).To(Succeed())
+			Expect(record.User).To(Equal("fake_user"))
+			Expect(record.Chain).To(Equal("release"))
+			Expect(record.From).To(Equal("dev"))
+			Expect(record.To).To(Equal("prod"))
+			Expect(record.Apps).To(Equal([]helpers.PromotedApp{
+				{Name: "fake_source_app", DropletGUID: "fake_droplet_guid", DropletChecksum: "fake_checksum"},
+			}))
+		})
+
+		It("Should record the source of the copied apps in annotations of the copies", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}}
+						apps[0].GUID = "fake_src_app_guid"
+						apps[0].Name = "fake_source_app"
+						apps[0].State = "started"
+						return
+					},
+				}
+			}
+			// The copy is seen at the destination once it is started
+			copied := false
+			fakeDestApplications := &applicationsfakes.FakeRepository{
+				UpdateStub: func(appGUID string, params models.AppParams) (models.Application, error) {
+					copied = true
+					return models.Application{}, nil
+				},
+			}
+			mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+			mockDestSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() ([]models.Application, error) {
+						if !copied {
+							return []models.Application{}, nil
+						}
+						destApp := models.Application{}
+						destApp.GUID = "fake_dest_app_guid"
+						destApp.Name = "fake_source_app"
+						return []models.Application{destApp}, nil
+					},
+				}
+			}
+			ccResponses["GET /v3/apps/fake_src_app_guid/droplets/current"] = `{"guid":"fake_droplet_guid"}`
+
+			var exitCode int
+			io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app"},
+					Force:          true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+
+			patches := []string{}
+			for _, r := range ccRequests {
+				if strings.HasPrefix(r, "PATCH /v3/apps/fake_dest_app_guid ") {
+					patches = append(patches, strings.TrimPrefix(r, "PATCH /v3/apps/fake_dest_app_guid "))
+				}
+			}
+			Expect(patches).To(HaveLen(1))
+
+			request := struct {
+				Metadata struct {
+					Annotations map[string]string `json:"annotations"`
+				} `json:"metadata"`
+			}{}
+			Expect(json.Unmarshal([]byte(patches[0]), &request)).To(Succeed())
+			provenance, ok := helpers.ParseProvenance(request.Metadata.Annotations)
+			Expect(ok).To(BeTrue())
+			Expect(provenance.SourceTarget).To(Equal("fake_source_target"))
+			Expect(provenance.SourceOrg).To(Equal("fake_src_org"))
+			Expect(provenance.SourceSpace).To(Equal("fake_src_space"))
+			Expect(provenance.SourceApp).To(Equal("fake_source_app"))
+			Expect(provenance.SourceGUID).To(Equal("fake_src_app_guid"))
+			Expect(provenance.SourceDroplet).To(Equal("fake_droplet_guid"))
+			Expect(provenance.CopiedAt).ToNot(BeZero())
+		})
+
+		It("Should only download the source apps once when they are copied to more than one destination", func() {
+			otherDestConfig := writeConfig("other_dest.json")
+			mockTargets.Targets["fake_other_dest_target"] = otherDestConfig
+			mockSessionProvider.MockSessionMap[otherDestConfig] = mockDestSession
+			srcSession := &downloadingSession{CfSession: mockSrcSession}
+			mockSessionProvider.MockSessionMap[mockTargets.Targets["fake_source_target"]] = srcSession
+			applicationsManager := &downloadingApplicationsManager{ApplicationsManager: mockApplicationsManager}
+			copyCommand = NewCopyCommand(mockTargets, mockSessionProvider, applicationsManager, mockServicesManager)
+
+			var exitCode int
+			io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					Destinations: []Destination{
+						{Target: "fake_dest_target", Org: "fake_dest_org", Space: "fake_dest_space"},
+						{Target: "fake_other_dest_target", Org: "fake_dest_org", Space: "fake_dest_space"},
+					},
+					SourceAppNames: []string{"fake_source_app"},
+					Force:          true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+			// The apps are collected with the sessions of each destination
+			Expect(applicationsManager.downloaded).To(Equal([]string{"fake_bits", "fake_bits"}))
+			Expect(srcSession.downloads).To(Equal(1))
+		})
+
+		It("Should only start the apps started at the source in the given start order", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}, models.Application{}}
+						apps[0].Name = "fake_source_app"
+						apps[0].State = "started"
+						apps[1].Name = "fake_app1"
+						apps[1].State = "stopped"
+						return
+					},
+				}
+			}
+			fakeDestApplications := &applicationsfakes.FakeRepository{}
+			mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+			mockDestSession.MockAppInstances = func() appinstances.Repository {
+				return &appinstancesfakes.FakeRepository{
+					GetInstancesStub: func(appGUID string) ([]models.AppInstanceFields, error) {
+						return []models.AppInstanceFields{{State: models.InstanceRunning}}, nil
+					},
+				}
+			}
+
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app", "fake_app1"},
+					StartTiers:     [][]string{{"fake_app1"}, {"fake_source_app"}},
+					Force:          true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+			Expect(output).To(ContainElement(MatchRegexp(`Starting apps .*fake_source_app.* in tier 1\.\.\.`)))
+			Expect(output).ToNot(ContainElement(MatchRegexp(`Starting apps .*fake_app1`)))
+			Expect(fakeDestApplications.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("Should only wait for the apps that were started", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}, models.Application{}}
+						apps[0].Name = "fake_source_app"
+						apps[0].State = "started"
+						apps[1].Name = "fake_app1"
+						apps[1].State = "stopped"
+						return
+					},
+				}
+			}
+			fakeDestApplications := &applicationsfakes.FakeRepository{}
+			mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+			fakeAppInstances := &appinstancesfakes.FakeRepository{
+				GetInstancesStub: func(appGUID string) ([]models.AppInstanceFields, error) {
+					return []models.AppInstanceFields{{State: models.InstanceRunning}}, nil
+				},
+			}
+			mockDestSession.MockAppInstances = func() appinstances.Repository { return fakeAppInstances }
+
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app", "fake_app1"},
+					Wait:           true,
+					Force:          true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+			Expect(fakeAppInstances.GetInstancesCallCount()).To(Equal(1))
+			Expect(output).To(ContainElement(MatchRegexp(`^fake_source_app\s+healthy`)))
+			Expect(output).ToNot(ContainElement(MatchRegexp(`^fake_app1\s`)))
+		})
+
+		It("Should not copy to a protected destination unless it is allowed", func() {
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app"},
+					Force:          true,
+					ProtectedDestinations: []helpers.ProtectedDestination{
+						{Org: "fake_dest_*", Space: "fake_dest_space"},
+					},
+				})
+			})
+			Expect(exitCode).To(Equal(ExitPreflightFailure))
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The destination space 'fake_dest_space' is protected by 'org fake_dest_* / space fake_dest_space'. Use --allow-protected to copy to it."))
+		})
+
+		It("Should report all selected apps that do not exist", func() {
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app", "fake_app1", "fake_app2"},
+				})
+			})
+			Expect(exitCode).To(Equal(ExitPreflightFailure))
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The application 'fake_app1' does not exist."))
+			Expect(output[2]).To(Equal("The application 'fake_app2' does not exist."))
+		})
+
+		It("Should fail if the scaled apps exceed what is left of the destination quota", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}}
+						apps[0].Name = "fake_source_app"
+						apps[0].State = "started"
+						return
+					},
+				}
+			}
+			mockDestSession.MockSpaces = func() spaces.SpaceRepository {
+				return &FakeSpaceRepository{
+					FindByNameInOrgStub: func(name, orgGUID string) (space models.Space, apiErr error) {
+						space = models.Space{}
+						space.GUID = "fake_dest_space_guid"
+						space.Name = name
+						space.SpaceQuotaGUID = "fake_space_quota_guid"
+						return
+					},
+				}
+			}
+			ccResponses["GET /v2/space_quota_definitions/fake_space_quota_guid"] =
+				`{"entity":{"memory_limit":1792,"instance_memory_limit":-1,"app_instance_limit":-1}}`
+			ccResponses["GET /v2/spaces/fake_dest_space_guid/summary"] =
+				`{"apps":[{"state":"STARTED","memory":256,"instances":2},{"state":"STOPPED","memory":1024,"instances":1}]}`
+
+			appScale := helpers.NewAppScale()
+			Expect(appScale.ParseInstances("3")).To(Succeed())
+			Expect(appScale.ParseMemory("512M")).To(Succeed())
+
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app"},
+					AppScale:       appScale,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitPreflightFailure))
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The copied apps require 1536M of memory but only 1280M of the destination space memory limit of 1792M is available."))
+		})
+
+		It("Should only count the started apps that do not replace apps in place against the destination quota", func() {
+			mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+				return &FakeAppSummaryRepository{
+					GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+						apps = []models.Application{models.Application{}, models.Application{}}
+						apps[0].Name = "fake_source_app"
+						apps[0].State = "started"
+						apps[1].Name = "fake_app1"
+						apps[1].State = "stopped"
+						return
+					},
+				}
+			}
+			mockDestSession.MockSpaces = func() spaces.SpaceRepository {
+				return &FakeSpaceRepository{
+					FindByNameInOrgStub: func(name, orgGUID string) (space models.Space, apiErr error) {
+						space = models.Space{}
+						space.GUID = "fake_dest_space_guid"
+						space.Name = name
+						space.SpaceQuotaGUID = "fake_space_quota_guid"
+						return
+					},
+				}
+			}
+			ccResponses["GET /v2/space_quota_definitions/fake_space_quota_guid"] =
+				`{"entity":{"memory_limit":1792,"instance_memory_limit":-1,"app_instance_limit":-1}}`
+			ccResponses["GET /v2/spaces/fake_dest_space_guid/summary"] =
+				`{"apps":[{"name":"fake_source_app","state":"STARTED","memory":512,"instances":2}]}`
+
+			appScale := helpers.NewAppScale()
+			Expect(appScale.ParseInstances("3")).To(Succeed())
+			Expect(appScale.ParseMemory("512M")).To(Succeed())
+
+			var exitCode int
+			io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app", "fake_app1"},
+					AppScale:       appScale,
+					Force:          true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+		})
+
+		It("Should warn and copy if the destination quota cannot be read", func() {
+			mockDestSession.MockSpaces = func() spaces.SpaceRepository {
+				return &FakeSpaceRepository{
+					FindByNameInOrgStub: func(name, orgGUID string) (space models.Space, apiErr error) {
+						space = models.Space{}
+						space.GUID = "fake_dest_space_guid"
+						space.Name = name
+						return
+					},
+				}
+			}
+			ccStatus["GET /v2/spaces/fake_dest_space_guid/summary"] = http.StatusForbidden
+
+			var exitCode int
+			output := io_helpers.CaptureOutput(func() {
+				exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+					DestSpace:      "fake_dest_space",
+					DestOrg:        "fake_dest_org",
+					DestTarget:     "fake_dest_target",
+					SourceAppNames: []string{"fake_source_app"},
+					Force:          true,
+				})
+			})
+			Expect(exitCode).To(Equal(ExitOK))
+			Expect(output).To(ContainElement(ContainSubstring("The destination quotas could not be checked")))
+		})
+
+		Context("With copies run by the plugin", func() {
+
+			var (
+				copyPlugin *CopyPlugin
+				dir        string
+			)
+
+			BeforeEach(func() {
+				destApp := models.Application{}
+				destApp.GUID = "fake_dest_app_guid"
+				destApp.Name = "fake_source_app"
+				mockDestSession.MockAppSummary = func() api.AppSummaryRepository {
+					return &FakeAppSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() ([]models.Application, error) {
+							return []models.Application{destApp}, nil
+						},
+					}
+				}
+				mockDestSession.MockApplications = func() applications.Repository { return &applicationsfakes.FakeRepository{} }
+
+				copyPlugin = NewCopyPlugin(copyCommand)
+				dir, _ = ioutil.TempDir("", "copy-profile")
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(dir)
+			})
+
+			It("Should apply the command line, the environment and the profile in that order", func() {
+				config := filepath.Join(dir, "copy.yml")
+				Expect(ioutil.WriteFile(config, []byte(cf_copy_dest_profiles), 0644)).To(Succeed())
+
+				os.Setenv("CF_COPY_ON_CONFLICT", "fail")
+				defer os.Unsetenv("CF_COPY_ON_CONFLICT")
+
+				// The apps of the command line are copied instead of those of
+				// the profile and the environment overrides the profile's skip
+				output := io_helpers.CaptureOutput(func() {
+					copyPlugin.Run(fakeCliConnection, []string{
+						"copy",
+						"--config", config,
+						"--profile", "to-dest",
+						"--apps", "fake_source_app",
+						"--force",
+					})
+				})
+				Expect(output).To(ContainElement("The app 'fake_source_app' already exists at the destination."))
+				Expect(copyPlugin.ExitCode()).To(Equal(ExitPreflightFailure))
+
+				// The command line overrides the environment
+				io_helpers.CaptureOutput(func() {
+					copyPlugin.Run(fakeCliConnection, []string{
+						"copy",
+						"--config", config,
+						"--profile", "to-dest",
+						"--apps", "fake_source_app",
+						"--on-conflict", "skip",
+						"--force",
+					})
+				})
+				Expect(copyPlugin.ExitCode()).To(Equal(ExitOK))
+			})
+
+			It("Should copy to the destination given by positional arguments between flags", func() {
+				var orgNames, spaceNames []string
+				mockDestSession.MockOrganizations = func() organizations.OrganizationRepository {
+					return &FakeOrganizationRepository{
+						FindByNameStub: func(name string) (org models.Organization, apiErr error) {
+							orgNames = append(orgNames, name)
+							org.GUID = "1234"
+							org.Name = name
+							return
+						},
+					}
+				}
+				mockDestSession.MockSpaces = func() spaces.SpaceRepository {
+					return &FakeSpaceRepository{
+						FindByNameInOrgStub: func(name, orgGUID string) (space models.Space, apiErr error) {
+							spaceNames = append(spaceNames, name)
+							space.Name = name
+							return
+						},
+					}
+				}
+
+				io_helpers.CaptureOutput(func() {
+					copyPlugin.Run(fakeCliConnection, []string{
+						"copy",
+						"--apps", "fake_source_app",
+						"fake_dest_space",
+						"--on-conflict", "skip",
+						"fake_dest_org",
+						"--force",
+						"fake_dest_target",
+					})
+				})
+				Expect(copyPlugin.ExitCode()).To(Equal(ExitOK))
+				Expect(orgNames).To(Equal([]string{"fake_dest_org"}))
+				Expect(spaceNames).To(Equal([]string{"fake_dest_space"}))
+			})
+
+			It("Should report all problems with the selected apps and services before copying", func() {
+				output := io_helpers.CaptureOutput(func() {
+					copyPlugin.Run(fakeCliConnection, []string{
+						"copy",
+						"--ups", "fake_svc",
+						"fake_dest_space", "fake_dest_org", "fake_dest_target",
+						"--apps", "fake_source_app,fake_app1",
+						"--force",
+					})
+				})
+				Expect(copyPlugin.ExitCode()).To(Equal(ExitPreflightFailure))
+				Expect(output).To(ContainElement("The application 'fake_app1' does not exist."))
+				Expect(output).To(ContainElement("The service 'fake_svc' to copy as a user provided service does not exist."))
+			})
+		})
+
+		Context("With apps and services that exist at the destination", func() {
+
+			var (
+				fakeDestApplications *applicationsfakes.FakeRepository
+				fakeServiceBindings  *apifakes.FakeServiceBindingRepository
+			)
+
+			BeforeEach(func() {
+				srcService := models.ServiceInstance{ApplicationNames: []string{"fake_source_app"}}
+				srcService.Name = "fake_service"
+				mockSrcSession.MockServiceSummary = func() api.ServiceSummaryRepository {
+					return &apifakes.FakeServiceSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() ([]models.ServiceInstance, error) {
+							return []models.ServiceInstance{srcService}, nil
+						},
+					}
+				}
+
+				destApp := models.Application{}
+				destApp.GUID = "fake_dest_app_guid"
+				destApp.Name = "fake_source_app"
+				mockDestSession.MockAppSummary = func() api.AppSummaryRepository {
+					return &FakeAppSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() ([]models.Application, error) {
+							return []models.Application{destApp}, nil
+						},
+					}
+				}
+				destService := models.ServiceInstance{}
+				destService.GUID = "fake_dest_service_guid"
+				destService.Name = "fake_service"
+				mockDestSession.MockServiceSummary = func() api.ServiceSummaryRepository {
+					return &apifakes.FakeServiceSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() ([]models.ServiceInstance, error) {
+							return []models.ServiceInstance{destService}, nil
+						},
+					}
+				}
+
+				fakeDestApplications = &applicationsfakes.FakeRepository{}
+				fakeDestApplications.ReadStub = func(name string) (app models.Application, err error) {
+					app.GUID = "fake_copied_app_guid"
+					app.Name = name
+					return
+				}
+				mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+
+				fakeServiceBindings = &apifakes.FakeServiceBindingRepository{}
+				mockDestSession.MockServiceBindings = func() api.ServiceBindingRepository { return fakeServiceBindings }
+			})
+
+			copyWithConflicts := func(appConflict, serviceConflict string) (exitCode int, output []string) {
+				output = io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:       "fake_dest_space",
+						DestOrg:         "fake_dest_org",
+						DestTarget:      "fake_dest_target",
+						SourceAppNames:  []string{"fake_source_app"},
+						AppConflict:     appConflict,
+						ServiceConflict: serviceConflict,
+						Force:           true,
+					})
+				})
+				return
+			}
+
+			It("Fails without changing the destination", func() {
+				exitCode, output := copyWithConflicts(ConflictFail, ConflictFail)
+				Expect(exitCode).To(Equal(ExitPreflightFailure))
+				Expect(output).To(ContainElement("The app 'fake_source_app' already exists at the destination."))
+				Expect(fakeDestApplications.DeleteCallCount()).To(Equal(0))
+				Expect(fakeDestApplications.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("Replaces the existing app", func() {
+				exitCode, output := copyWithConflicts(ConflictReplace, ConflictReplace)
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(output).To(ContainElement(ContainSubstring("Deleting existing app fake_source_app...")))
+				Expect(fakeDestApplications.DeleteCallCount()).To(Equal(1))
+				Expect(fakeDestApplications.DeleteArgsForCall(0)).To(Equal("fake_dest_app_guid"))
+			})
+
+			It("Renames the existing app", func() {
+				exitCode, _ := copyWithConflicts(ConflictRename, ConflictSkip)
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(fakeDestApplications.DeleteCallCount()).To(Equal(0))
+				Expect(fakeDestApplications.UpdateCallCount()).To(Equal(1))
+				appGUID, params := fakeDestApplications.UpdateArgsForCall(0)
+				Expect(appGUID).To(Equal("fake_dest_app_guid"))
+				Expect(*params.Name).To(MatchRegexp(`^fake_source_app-\d{14}$`))
+			})
+
+			It("Keeps the existing app and leaves it out of the copy", func() {
+				exitCode, output := copyWithConflicts(ConflictSkip, ConflictSkip)
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(output).To(ContainElement(ContainSubstring("kept existing")))
+				Expect(fakeDestApplications.DeleteCallCount()).To(Equal(0))
+				Expect(fakeDestApplications.UpdateCallCount()).To(Equal(0))
+				Expect(fakeServiceBindings.CreateCallCount()).To(Equal(0))
+			})
+
+			It("Does not annotate the existing services kept at the destination", func() {
+				exitCode, _ := copyWithConflicts(ConflictReplace, ConflictSkip)
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(ccRequests).ToNot(ContainElement(HavePrefix("PATCH /v3/service_instances/fake_dest_service_guid")))
+			})
+
+			It("Switches the routes of an app replaced blue-green once its copy is healthy", func() {
+				route := models.RouteSummary{GUID: "fake_route_guid", Host: "fake-host"}
+				destApp := models.Application{}
+				destApp.GUID = "fake_dest_app_guid"
+				destApp.Name = "fake_source_app"
+				destApp.Routes = []models.RouteSummary{route}
+				mockDestSession.MockAppSummary = func() api.AppSummaryRepository {
+					return &FakeAppSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() ([]models.Application, error) {
+							return []models.Application{destApp}, nil
+						},
+					}
+				}
+				mockDestSession.MockAppInstances = func() appinstances.Repository {
+					return &appinstancesfakes.FakeRepository{
+						GetInstancesStub: func(appGUID string) ([]models.AppInstanceFields, error) {
+							return []models.AppInstanceFields{{State: models.InstanceRunning}}, nil
+						},
+					}
+				}
+				fakeRoutes := &apifakes.FakeRouteRepository{}
+				mockDestSession.MockRoutes = func() api.RouteRepository { return fakeRoutes }
+
+				var exitCode int
+				io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:      "fake_dest_space",
+						DestOrg:        "fake_dest_org",
+						DestTarget:     "fake_dest_target",
+						SourceAppNames: []string{"fake_source_app"},
+						BlueGreen:      true,
+						Force:          true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitOK))
+
+				Expect(fakeRoutes.BindCallCount()).To(Equal(1))
+				routeGUID, appGUID := fakeRoutes.BindArgsForCall(0)
+				Expect(routeGUID).To(Equal("fake_route_guid"))
+				Expect(appGUID).To(Equal("fake_copied_app_guid"))
+				Expect(fakeRoutes.UnbindCallCount()).To(Equal(1))
+				routeGUID, appGUID = fakeRoutes.UnbindArgsForCall(0)
+				Expect(routeGUID).To(Equal("fake_route_guid"))
+				Expect(appGUID).To(Equal("fake_dest_app_guid"))
+
+				Expect(fakeDestApplications.UpdateCallCount()).To(Equal(2))
+				appGUID, params := fakeDestApplications.UpdateArgsForCall(0)
+				Expect(appGUID).To(Equal("fake_dest_app_guid"))
+				Expect(*params.Name).To(MatchRegexp(`^fake_source_app-venerable-\d{14}$`))
+				appGUID, params = fakeDestApplications.UpdateArgsForCall(1)
+				Expect(appGUID).To(Equal("fake_copied_app_guid"))
+				Expect(*params.Name).To(Equal("fake_source_app"))
+
+				Expect(fakeDestApplications.DeleteCallCount()).To(Equal(1))
+				Expect(fakeDestApplications.DeleteArgsForCall(0)).To(Equal("fake_dest_app_guid"))
+			})
+
+			It("Rejects rolling out a copy of an app that is stopped at the source", func() {
+				var exitCode int
+				output := io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:      "fake_dest_space",
+						DestOrg:        "fake_dest_org",
+						DestTarget:     "fake_dest_target",
+						SourceAppNames: []string{"fake_source_app"},
+						Strategy:       StrategyRolling,
+						Force:          true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitPreflightFailure))
+				Expect(output).To(ContainElement("The app 'fake_source_app' cannot be rolled out with --strategy rolling as it is stopped at the source."))
+				Expect(fakeDestApplications.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("Starts the intermediate copy of a rolling replacement without routes", func() {
+				mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+					return &FakeAppSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+							apps = []models.Application{models.Application{}}
+							apps[0].Name = "fake_source_app"
+							apps[0].State = "started"
+							return
+						},
+					}
+				}
+				events := []string{}
+				fakeDestApplications.UpdateStub = func(appGUID string, params models.AppParams) (models.Application, error) {
+					if params.State != nil {
+						events = append(events, "update "+appGUID+" "+*params.State)
+					}
+					return models.Application{}, nil
+				}
+				fakeRoutes := &apifakes.FakeRouteRepository{
+					UnbindStub: func(routeGUID, appGUID string) error {
+						events = append(events, "unbind "+routeGUID+" "+appGUID)
+						return nil
+					},
+				}
+				mockDestSession.MockRoutes = func() api.RouteRepository { return fakeRoutes }
+
+				ccResponses["GET /v2/apps/fake_copied_app_guid/routes"] =
+					`{"resources":[{"metadata":{"guid":"fake_route_guid"}}]}`
+				ccResponses["GET /v3/apps/fake_copied_app_guid/droplets/current"] = `{"guid":"fake_droplet_guid","state":"STAGED"}`
+				ccResponses["POST /v3/droplets"] = `{"guid":"fake_rolled_droplet_guid","state":"STAGED"}`
+				ccResponses["POST /v3/deployments"] = `{"guid":"fake_deployment_guid","state":"DEPLOYED"}`
+
+				var exitCode int
+				io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:      "fake_dest_space",
+						DestOrg:        "fake_dest_org",
+						DestTarget:     "fake_dest_target",
+						SourceAppNames: []string{"fake_source_app"},
+						Strategy:       StrategyRolling,
+						Force:          true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(events[:2]).To(Equal([]string{
+					"unbind fake_route_guid fake_copied_app_guid",
+					"update fake_copied_app_guid started",
+				}))
+				Expect(fakeDestApplications.DeleteCallCount()).To(Equal(1))
+				Expect(fakeDestApplications.DeleteArgsForCall(0)).To(Equal("fake_copied_app_guid"))
+			})
+
+			It("Binds the copied app to the existing service it keeps", func() {
+				exitCode, _ := copyWithConflicts(ConflictReplace, ConflictSkip)
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(fakeServiceBindings.CreateCallCount()).To(Equal(1))
+				serviceGUID, appGUID, _ := fakeServiceBindings.CreateArgsForCall(0)
+				Expect(serviceGUID).To(Equal("fake_dest_service_guid"))
+				Expect(appGUID).To(Equal("fake_copied_app_guid"))
+			})
+		})
+
+		Context("With an org copied space by space", func() {
+
+			BeforeEach(func() {
+				ccResponses["GET /v3/organizations"] = `{"resources":[{"guid":"fake_org_guid","name":"fake_org"}]}`
+				ccResponses["GET /v3/spaces"] = `{"resources":[` +
+					`{"guid":"fake_space1_guid","name":"fake_space1"},` +
+					`{"guid":"fake_space2_guid","name":"fake_space2"}]}`
+
+				// The source session lists the apps of the space it is set to
+				srcSpace := models.SpaceFields{}
+				mockSrcSession.MockGetSessionSpace = func() models.SpaceFields { return srcSpace }
+				mockSrcSession.MockSetSessionSpace = func(space models.SpaceFields) { srcSpace = space }
+				mockSrcSession.MockSetSessionOrg = func(org models.OrganizationFields) {}
+				mockSrcSession.MockOrganizations = func() organizations.OrganizationRepository {
+					return &FakeOrganizationRepository{
+						FindByNameStub: func(name string) (org models.Organization, apiErr error) {
+							org = models.Organization{}
+							org.GUID = "fake_src_org_guid"
+							org.Name = name
+							return
+						},
+					}
+				}
+				mockSrcSession.MockSpaces = func() spaces.SpaceRepository {
+					return &FakeSpaceRepository{
+						FindByNameInOrgStub: func(name, orgGUID string) (space models.Space, apiErr error) {
+							space = models.Space{}
+							space.Name = name
+							return
+						},
+					}
+				}
+				mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+					return &FakeAppSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() (apps []models.Application, err error) {
+							apps = []models.Application{models.Application{}}
+							apps[0].Name = srcSpace.Name + "_app"
+							apps[0].State = "started"
+							return
+						},
+					}
+				}
+
+				mockDestSession.MockSpaces = func() spaces.SpaceRepository {
+					return &FakeSpaceRepository{
+						FindByNameInOrgStub: func(name, orgGUID string) (space models.Space, apiErr error) {
+							space = models.Space{}
+							space.Name = name
+							return
+						},
+					}
+				}
+				mockDestSession.MockSetSessionSpace = func(space models.SpaceFields) {}
+				fakeDestApplications := &applicationsfakes.FakeRepository{}
+				mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+			})
+
+			It("Copies the apps of each space to the space with the same name", func() {
+				var exitCode int
+				output := io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						SourceOrg:  "fake_src_org",
+						DestOrg:    "fake_dest_org",
+						DestTarget: "fake_dest_target",
+						OrgCopy:    &OrgCopy{},
+						Force:      true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitOK))
+
+				started := []string{}
+				for _, line := range output {
+					if m := startStep.FindStringSubmatch(line); m != nil {
+						started = append(started, m[1])
+					}
+				}
+				Expect(started).To(Equal([]string{"fake_space1_app", "fake_space2_app"}))
+			})
+			It("Copies the spaces listed on every page", func() {
+				ccResponses["GET /v3/spaces"] = `{` +
+					`"pagination":{"next":{"href":"https://api.fake.com/v3/spaces?organization_guids=fake_org_guid&page=2&per_page=5000"}},` +
+					`"resources":[{"guid":"fake_space1_guid","name":"fake_space1"}]}`
+				ccHandler = func(w http.ResponseWriter, r *http.Request) bool {
+					if r.URL.Path != "/v3/spaces" || r.URL.Query().Get("page") != "2" {
+						return false
+					}
+					fmt.Fprint(w, `{"pagination":{"next":null},"resources":[{"guid":"fake_space2_guid","name":"fake_space2"}]}`)
+					return true
+				}
+
+				var exitCode int
+				output := io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						SourceOrg:  "fake_src_org",
+						DestOrg:    "fake_dest_org",
+						DestTarget: "fake_dest_target",
+						OrgCopy:    &OrgCopy{},
+						Force:      true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(output).To(ContainElement(MatchRegexp(`^\[\d+/\d+\] .*fake_space2_app.*: start\.\.\.$`)))
+			})
+
+			It("Leaves out the space_supporter role when the destination does not support it", func() {
+				ccHandler = func(w http.ResponseWriter, r *http.Request) bool {
+					if r.URL.Path != "/v3/roles" || !strings.Contains(r.URL.Query().Get("types"), "space_supporter") {
+						return false
+					}
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprint(w, `{"errors":[{"code":10005,"title":"CF-BadQueryParameter"}]}`)
+					return true
+				}
+
+				var exitCode int
+				output := io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						SourceOrg:  "fake_src_org",
+						DestOrg:    "fake_dest_org",
+						DestTarget: "fake_dest_target",
+						OrgCopy:    &OrgCopy{},
+						Force:      true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(output).To(ContainElement(ContainSubstring(
+					"The target 'fake_dest_target' does not support the space_supporter role so it is not copied.")))
+				Expect(ccRequests).To(ContainElement(MatchRegexp(`^GET /v3/roles\?space_guids=.*&types=space_manager,space_developer,space_auditor&`)))
+			})
+		})
+
+		Context("With droplets copied within the same target", func() {
+
+			var (
+				fakeSrcApplications  *applicationsfakes.FakeRepository
+				fakeDestApplications *applicationsfakes.FakeRepository
+			)
+
+			BeforeEach(func() {
+				// Both sessions are created from the config of the same target
+				copyCommand = NewCopyCommand(mockTargets,
+					&orderedSessionProvider{sessions: []cfapi.CfSession{mockSrcSession, mockDestSession}},
+					mockApplicationsManager, mockServicesManager)
+
+				mockSrcSession.MockSetSessionOrg = func(org models.OrganizationFields) {}
+				mockSrcSession.MockSetSessionSpace = func(space models.SpaceFields) {}
+				fakeSrcApplications = &applicationsfakes.FakeRepository{
+					ReadStub: func(name string) (app models.Application, err error) {
+						app.GUID = "fake_src_app_guid"
+						app.Name = name
+						return
+					},
+				}
+				mockSrcSession.MockApplications = func() applications.Repository { return fakeSrcApplications }
+				fakeDestApplications = &applicationsfakes.FakeRepository{}
+				mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+
+				ccResponses["GET /v3/apps/fake_src_app_guid/droplets/current"] = `{"guid":"fake_droplet_guid","state":"STAGED"}`
+				ccResponses["POST /v3/droplets"] = `{"guid":"fake_copied_droplet_guid","state":"STAGED"}`
+			})
+
+			It("Updates an app that was copied before and replaces its droplet", func() {
+				fakeDestApplications.ReadStub = func(name string) (app models.Application, err error) {
+					app.GUID = "fake_dest_app_guid"
+					app.Name = name
+					return
+				}
+				fakeDestApplications.UpdateStub = func(appGUID string, params models.AppParams) (app models.Application, err error) {
+					app.GUID = appGUID
+					return
+				}
+
+				var exitCode int
+				io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:      "fake_dest_space",
+						DestOrg:        "fake_dest_org",
+						DestTarget:     "fake_source_target",
+						SourceAppNames: []string{"fake_source_app"},
+						CopyAsDroplet:  true,
+						Force:          true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitOK))
+
+				Expect(fakeDestApplications.CreateCallCount()).To(Equal(0))
+				Expect(fakeDestApplications.UpdateCallCount()).To(Equal(1))
+				appGUID, params := fakeDestApplications.UpdateArgsForCall(0)
+				Expect(appGUID).To(Equal("fake_dest_app_guid"))
+				Expect(params.SpaceGUID).To(BeNil())
+				Expect(ccRequests).To(ContainElement(HavePrefix("POST /v3/droplets?source_guid=fake_droplet_guid")))
+				Expect(ccRequests).To(ContainElement(
+					`PATCH /v3/apps/fake_dest_app_guid/relationships/current_droplet {"data":{"guid":"fake_copied_droplet_guid"}}`))
+			})
+
+			It("Creates an app that does not exist at the destination", func() {
+				fakeDestApplications.ReadReturns(models.Application{}, cferrors.NewModelNotFoundError("App", "fake_source_app"))
+				fakeDestApplications.CreateStub = func(params models.AppParams) (app models.Application, err error) {
+					app.GUID = "fake_copied_app_guid"
+					app.Name = *params.Name
+					return
+				}
+
+				var exitCode int
+				io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:      "fake_dest_space",
+						DestOrg:        "fake_dest_org",
+						DestTarget:     "fake_source_target",
+						SourceAppNames: []string{"fake_source_app"},
+						CopyAsDroplet:  true,
+						Force:          true,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitOK))
+				Expect(fakeDestApplications.CreateCallCount()).To(Equal(1))
+				Expect(ccRequests).To(ContainElement(HavePrefix("PATCH /v3/apps/fake_copied_app_guid/relationships/current_droplet")))
+			})
+		})
+
+		Context("With apps moved within the same target", func() {
+
+			var (
+				srcApp               models.Application
+				fakeSrcApplications  *applicationsfakes.FakeRepository
+				fakeDestApplications *applicationsfakes.FakeRepository
+				fakeAppInstances     *appinstancesfakes.FakeRepository
+				fakeRoutes           *apifakes.FakeRouteRepository
+			)
+
+			BeforeEach(func() {
+				// Both sessions are created from the config of the same target
+				copyCommand = NewCopyCommand(mockTargets,
+					&orderedSessionProvider{sessions: []cfapi.CfSession{mockSrcSession, mockDestSession}},
+					mockApplicationsManager, mockServicesManager)
+
+				srcApp = models.Application{}
+				srcApp.GUID = "fake_src_app_guid"
+				srcApp.Name = "fake_source_app"
+				srcApp.State = "started"
+				srcApp.Routes = []models.RouteSummary{{GUID: "fake_route_guid", Host: "fake-host", Port: 1024}}
+				mockSrcSession.MockAppSummary = func() api.AppSummaryRepository {
+					return &FakeAppSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() ([]models.Application, error) {
+							return []models.Application{srcApp}, nil
+						},
+					}
+				}
+				mockSrcSession.MockGetSessionSpace = func() models.SpaceFields {
+					return models.SpaceFields{GUID: "fake_src_space_guid", Name: "fake_src_space"}
+				}
+				mockSrcSession.MockSetSessionOrg = func(org models.OrganizationFields) {}
+				mockSrcSession.MockSetSessionSpace = func(space models.SpaceFields) {}
+				fakeSrcApplications = &applicationsfakes.FakeRepository{}
+				mockSrcSession.MockApplications = func() applications.Repository { return fakeSrcApplications }
+				fakeRoutes = &apifakes.FakeRouteRepository{
+					UnbindStub: func(routeGUID, appGUID string) error {
+						ccMutex.Lock()
+						ccRequests = append(ccRequests, "UNBIND "+routeGUID+" "+appGUID)
+						ccMutex.Unlock()
+						return nil
+					},
+				}
+				mockSrcSession.MockRoutes = func() api.RouteRepository { return fakeRoutes }
+
+				fakeDestApplications = &applicationsfakes.FakeRepository{}
+				mockDestSession.MockApplications = func() applications.Repository { return fakeDestApplications }
+				mockDestSession.MockAppSummary = func() api.AppSummaryRepository {
+					return &FakeAppSummaryRepository{
+						GetSummariesInCurrentSpaceStub: func() ([]models.Application, error) {
+							destApp := models.Application{}
+							destApp.GUID = "fake_dest_app_guid"
+							destApp.Name = "fake_source_app"
+							return []models.Application{destApp}, nil
+						},
+					}
+				}
+				fakeAppInstances = &appinstancesfakes.FakeRepository{
+					GetInstancesStub: func(appGUID string) ([]models.AppInstanceFields, error) {
+						return []models.AppInstanceFields{{State: models.InstanceRunning}}, nil
+					},
+				}
+				mockDestSession.MockAppInstances = func() appinstances.Repository { return fakeAppInstances }
+				mockDestSession.MockSpaces = func() spaces.SpaceRepository {
+					return &FakeSpaceRepository{
+						FindByNameInOrgStub: func(name, orgGUID string) (space models.Space, apiErr error) {
+							space = models.Space{}
+							space.GUID = "fake_dest_space_guid"
+							space.Name = name
+							return
+						},
+					}
+				}
+				ccResponses["GET /v3/feature_flags/route_sharing"] = `{"name":"route_sharing","enabled":true}`
+			})
+
+			move := func() (exitCode int) {
+				io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:      "fake_dest_space",
+						DestOrg:        "fake_dest_org",
+						DestTarget:     "fake_source_target",
+						SourceAppNames: []string{"fake_source_app"},
+						Move:           true,
+						RemapRoutes:    true,
+						Wait:           true,
+						Force:          true,
+					})
+				})
+				return
+			}
+
+			It("Maps a route to the copy before it is removed from the source app", func() {
+				ccResponses["POST /v3/routes/fake_route_guid/destinations"] =
+					`{"destinations":[{"guid":"fake_destination_guid","app":{"guid":"fake_dest_app_guid"}}]}`
+
+				Expect(move()).To(Equal(ExitOK))
+
+				moved := []string{}
+				for _, r := range ccRequests {
+					if strings.Contains(r, "fake_route_guid") {
+						moved = append(moved, strings.SplitN(r, " {", 2)[0])
+					}
+				}
+				Expect(moved).To(Equal([]string{
+					"POST /v3/routes/fake_route_guid/relationships/shared_spaces",
+					"POST /v3/routes/fake_route_guid/destinations",
+					"UNBIND fake_route_guid fake_src_app_guid",
+					"PATCH /v3/routes/fake_route_guid/transfer_owner",
+					"DELETE /v3/routes/fake_route_guid/relationships/shared_spaces/fake_src_space_guid",
+				}))
+				Expect(ccRequests).To(ContainElement(ContainSubstring(`"guid":"fake_dest_space_guid"`)))
+				Expect(fakeRoutes.DeleteCallCount()).To(Equal(0))
+
+				Expect(fakeSrcApplications.UpdateCallCount()).To(Equal(1))
+				appGUID, params := fakeSrcApplications.UpdateArgsForCall(0)
+				Expect(appGUID).To(Equal("fake_src_app_guid"))
+				Expect(*params.State).To(Equal("stopped"))
+			})
+
+			It("Moves an app stopped at the source without waiting for its copy to start", func() {
+				srcApp.State = "stopped"
+				ccResponses["POST /v3/routes/fake_route_guid/destinations"] =
+					`{"destinations":[{"guid":"fake_destination_guid","app":{"guid":"fake_dest_app_guid"}}]}`
+
+				Expect(move()).To(Equal(ExitOK))
+				Expect(fakeAppInstances.GetInstancesCallCount()).To(Equal(0))
+				Expect(ccRequests).To(ContainElement(HavePrefix("PATCH /v3/routes/fake_route_guid/transfer_owner")))
+				Expect(fakeSrcApplications.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("Does not move a route to a copy whose instances are not running", func() {
+				ccResponses["POST /v3/routes/fake_route_guid/destinations"] =
+					`{"destinations":[{"guid":"fake_destination_guid","app":{"guid":"fake_dest_app_guid"}}]}`
+				// The copy is healthy when the move waits for it but crashes before the route is moved
+				fakeAppInstances.GetInstancesStub = func(appGUID string) ([]models.AppInstanceFields, error) {
+					if fakeAppInstances.GetInstancesCallCount() > 1 {
+						return []models.AppInstanceFields{{State: models.InstanceCrashed}}, nil
+					}
+					return []models.AppInstanceFields{{State: models.InstanceRunning}}, nil
+				}
+
+				var exitCode int
+				output := io_helpers.CaptureOutput(func() {
+					exitCode = copyCommand.Execute(fakeCliConnection, &CopyOptions{
+						DestSpace:      "fake_dest_space",
+						DestOrg:        "fake_dest_org",
+						DestTarget:     "fake_source_target",
+						SourceAppNames: []string{"fake_source_app"},
+						Move:           true,
+						RemapRoutes:    true,
+						Wait:           true,
+						Force:          true,
+						Timeout:        time.Millisecond,
+					})
+				})
+				Expect(exitCode).To(Equal(ExitPartialCopy))
+				Expect(output).To(ContainElement(ContainSubstring("Not all instances of the copied app 'fake_source_app' are running.")))
+				Expect(ccRequests).To(ContainElement("DELETE /v3/routes/fake_route_guid/destinations/fake_destination_guid"))
+				Expect(fakeRoutes.UnbindCallCount()).To(Equal(0))
+			})
+
+			It("Fails before copying if routes cannot be shared", func() {
+				ccResponses["GET /v3/feature_flags/route_sharing"] = `{"name":"route_sharing","enabled":false}`
+
+				var exitCode int
+				output := io_helpers.CaptureOutput(func() {
+					exitCode = move()
+				})
+				Expect(exitCode).To(Equal(ExitPreflightFailure))
+				Expect(output).To(ContainElement(
+					"Routes can only be moved when the 'route_sharing' feature flag is enabled. It can be enabled by an admin with 'cf enable-feature-flag route_sharing'."))
+				Expect(fakeDestApplications.UpdateCallCount()).To(Equal(0))
+			})
+
+			It("Leaves a route that cannot be mapped to the copy with the source app", func() {
+				Expect(move()).To(Equal(ExitPartialCopy))
+
+				Expect(ccRequests).To(ContainElement(
+					"DELETE /v3/routes/fake_route_guid/relationships/shared_spaces/fake_dest_space_guid"))
+				Expect(ccRequests).ToNot(ContainElement(ContainSubstring("transfer_owner")))
+				Expect(fakeRoutes.UnbindCallCount()).To(Equal(0))
+				Expect(fakeSrcApplications.UpdateCallCount()).To(Equal(0))
+				Expect(fakeSrcApplications.DeleteCallCount()).To(Equal(0))
+			})
 		})
 	})
 })
 
+// startStep - Matches the step reporting the start of a copied app
+var startStep = regexp.MustCompile(`^\[\d+/\d+\] .*?(fake_\w+_app).*: start\.\.\.$`)
+
+// orderedSessionProvider - Returns the given sessions in the order they
+// are created regardless of the target config they are created from
+type orderedSessionProvider struct {
+	cfapi.CfSessionProvider
+	sessions []cfapi.CfSession
+}
+
+func (p *orderedSessionProvider) NewCfSessionFromFilepath(configPath string, sslDisabled bool, logger *cfapi.Logger) (session cfapi.CfSession, err error) {
+	session, p.sessions = p.sessions[0], p.sessions[1:]
+	return
+}
+
 const cf_plugins_out_1 = `Listing Installed Plugins...
 OK
 
@@ -200,3 +1566,57 @@ cf-targets         1.1.0     set-target         Set current target
 cf-targets         1.1.0     save-target        Save current target
 cf-targets         1.1.0     delete-target      Delete a saved target
 `
+
+const cf_copy_dest_profiles = `profiles:
+  to-dest:
+    target: fake_dest_target
+    org: fake_dest_org
+    space: fake_dest_space
+    apps: [fake_app1]
+    on-conflict: skip
+`
+
+// downloadingSession - Counts the downloads of the content of apps
+type downloadingSession struct {
+	cfapi.CfSession
+	downloads int
+}
+
+func (s *downloadingSession) DownloadAppContent(appGUID string, outputFile *os.File, asDroplet bool) error {
+	s.downloads++
+	_, err := outputFile.WriteString("fake_bits")
+	return err
+}
+
+// downloadingApplicationsManager - Downloads the source app with the
+// source session it is initialized with when the apps are collected
+type downloadingApplicationsManager struct {
+	copy.ApplicationsManager
+	srcCCSession cfapi.CfSession
+	downloaded   []string
+}
+
+func (m *downloadingApplicationsManager) Init(srcCCSession, destCCSession cfapi.CfSession, logger *cfapi.Logger) error {
+	m.srcCCSession = srcCCSession
+	return m.ApplicationsManager.Init(srcCCSession, destCCSession, logger)
+}
+
+func (m *downloadingApplicationsManager) ApplicationsToBeCopied(appNames []string, copyAsDroplet bool) (copy.ApplicationCollection, error) {
+
+	file, err := ioutil.TempFile("", "fake_app")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err = m.srcCCSession.DownloadAppContent("fake_src_app_guid", file, copyAsDroplet); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		return nil, err
+	}
+	m.downloaded = append(m.downloaded, string(data))
+	return m.ApplicationsManager.ApplicationsToBeCopied(appNames, copyAsDroplet)
+}
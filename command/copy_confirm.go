@@ -0,0 +1,122 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// protectedDestination - Returns the protected destination
+// the copy writes to or nil if the destination is not protected
+func (c *CopyCommand) protectedDestination() *helpers.ProtectedDestination {
+	for _, p := range c.o.ProtectedDestinations {
+		if p.Matches(c.o.DestTarget, c.destOrg.Name, c.destSpace.Name) {
+			return &p
+		}
+	}
+	return nil
+}
+
+// checkProtectedDestination - Fails if the destination is protected
+// and copying to it has not been allowed with --allow-protected
+func (c *CopyCommand) checkProtectedDestination() error {
+
+	p := c.protectedDestination()
+	if p == nil || c.o.AllowProtected {
+		return nil
+	}
+	return fmt.Errorf("The destination space '%s' is protected by '%s'. Use --allow-protected to copy to it.",
+		c.destSpace.Name, p.String())
+}
+
+// confirmCopy - Shows what will be copied and asks for confirmation
+// before anything is changed at the destination. The copy is confirmed
+// without asking if --force is set.
+func (c *CopyCommand) confirmCopy() (bool, error) {
+
+	if c.o.Force {
+		return true, nil
+	}
+	if !helpers.IsTerminal(os.Stdin) {
+		return false, errors.New("The copy cannot be confirmed as the input is not a terminal. Use --force to copy without confirmation.")
+	}
+
+	services, err := c.servicesToBeCopied()
+	if err != nil {
+		return false, err
+	}
+
+	table := c.logger.UI.Table([]string{"", ""})
+	table.Add(terminal.HeaderColor("destination"), fmt.Sprintf("target %s / org %s / space %s",
+		terminal.EntityNameColor(c.o.DestTarget),
+		terminal.EntityNameColor(c.destOrg.Name),
+		terminal.EntityNameColor(c.destSpace.Name)))
+	if !c.o.ServicesOnly {
+		table.Add(terminal.HeaderColor("apps"), summaryList(c.o.SourceAppNames))
+	}
+	table.Add(terminal.HeaderColor("services"), summaryList(services))
+	if !c.o.ServicesOnly {
+		mode := "push application bits"
+		if c.o.CopyAsDroplet {
+			mode = "copy droplets"
+		}
+		switch {
+		case c.o.BlueGreen:
+			mode += ", replace existing apps blue-green"
+		case c.o.Strategy == StrategyRolling:
+			mode += ", roll out to existing apps"
+		}
+		switch {
+		case c.o.Move && c.o.DeleteSource:
+			mode += ", then delete the source apps"
+		case c.o.Move:
+			mode += ", then stop the source apps"
+		}
+		table.Add(terminal.HeaderColor("mode"), mode)
+	}
+	if len(c.conflicts) > 0 {
+		table.Add(terminal.HeaderColor("existing"), fmt.Sprintf("%d apps and services", len(c.conflicts)))
+	}
+
+	c.logger.UI.Say("")
+	table.Print()
+	c.logger.UI.Say("")
+	if c.protectedDestination() != nil {
+		c.logger.UI.Warn("The destination is protected.")
+	}
+	verb := "copy"
+	if c.o.Move {
+		verb = "move"
+	}
+	return c.logger.UI.Confirm(fmt.Sprintf("Really %s to space %s?",
+		verb, terminal.EntityNameColor(c.destSpace.Name))), nil
+}
+
+// servicesToBeCopied - Returns the names of the source service
+// instances bound to the applications to be copied
+func (c *CopyCommand) servicesToBeCopied() (names []string, err error) {
+
+	var services []models.ServiceInstance
+
+	if services, err = c.srcCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	for _, s := range services {
+		if c.isServiceCopied(s) {
+			names = append(names, s.Name)
+		}
+	}
+	return
+}
+
+func summaryList(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
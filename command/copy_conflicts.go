@@ -0,0 +1,264 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+)
+
+// Strategies for resolving apps or services that
+// already exist at the destination
+const (
+	ConflictSkip    = "skip"
+	ConflictReplace = "replace"
+	ConflictRename  = "rename"
+	ConflictFail    = "fail"
+)
+
+// conflict - An app or service that exists at the
+// destination and how it was resolved
+type conflict struct {
+	kind   string
+	name   string
+	action string
+	result string
+
+	app     models.Application
+	service models.ServiceInstance
+
+	// The copied apps bound to a conflicting service at the source
+	boundApps []string
+}
+
+// ParseConflictStrategies - Parses the value of the '--on-conflict'
+// option which is either a strategy applied to both apps and services
+// or a comma separated list of 'apps=STRATEGY' and 'services=STRATEGY'
+func ParseConflictStrategies(value string) (appStrategy, serviceStrategy string, err error) {
+
+	if isConflictStrategy(value) {
+		return value, value, nil
+	}
+	for _, item := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(kv) != 2 || !isConflictStrategy(kv[1]) {
+			err = fmt.Errorf("invalid conflict strategy '%s', expected one of skip, replace, rename or fail", item)
+			return
+		}
+		switch kv[0] {
+		case "apps":
+			appStrategy = kv[1]
+		case "services":
+			serviceStrategy = kv[1]
+		default:
+			err = fmt.Errorf("invalid conflict resource '%s', expected apps or services", kv[0])
+			return
+		}
+	}
+	return
+}
+
+func isConflictStrategy(value string) bool {
+	switch value {
+	case ConflictSkip, ConflictReplace, ConflictRename, ConflictFail:
+		return true
+	}
+	return false
+}
+
+// findConflicts - Finds the apps and services to be copied that
+// already exist at the destination. Nothing is changed at the
+// destination until the conflicts are resolved.
+func (c *CopyCommand) findConflicts() (err error) {
+
+	var (
+		destApps     []models.Application
+		srcServices  []models.ServiceInstance
+		destServices []models.ServiceInstance
+	)
+
+	c.conflicts = []conflict{}
+	c.serviceFilter.Exclude(nil)
+
+	if c.o.AppConflict != "" && !c.o.ServicesOnly {
+		if destApps, err = c.destCCSession.AppSummary().GetSummariesInCurrentSpace(); err != nil {
+			return
+		}
+		appNames := []string{}
+		for _, name := range c.o.SourceAppNames {
+			copyApp := true
+			for _, a := range destApps {
+				if a.Name == name {
+					c.conflicts = append(c.conflicts, conflict{kind: "app", name: name, action: c.o.AppConflict, app: a})
+					copyApp = c.o.AppConflict != ConflictSkip
+					break
+				}
+			}
+			if copyApp {
+				appNames = append(appNames, name)
+			}
+		}
+		c.o.SourceAppNames = appNames
+	}
+
+	if c.o.ServiceConflict != "" {
+		if srcServices, err = c.srcCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+			return
+		}
+		if destServices, err = c.destCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+			return
+		}
+		skipped := []string{}
+		for _, s := range srcServices {
+			if !c.isServiceCopied(s) {
+				continue
+			}
+			for _, d := range destServices {
+				if d.Name == s.Name {
+					c.conflicts = append(c.conflicts, conflict{
+						kind:      "service",
+						name:      s.Name,
+						action:    c.o.ServiceConflict,
+						service:   d,
+						boundApps: s.ApplicationNames,
+					})
+					if c.o.ServiceConflict == ConflictSkip {
+						skipped = append(skipped, s.Name)
+					}
+					break
+				}
+			}
+		}
+		c.serviceFilter.Exclude(skipped)
+		c.o.RecreateServices = c.o.ServiceConflict == ConflictReplace
+	}
+
+	if len(c.conflicts) == 0 {
+		return
+	}
+	c.printConflicts("The following apps and services already exist at the destination:", false)
+
+	for _, cf := range c.conflicts {
+		if cf.action == ConflictFail {
+			return fmt.Errorf("The %s '%s' already exists at the destination.", cf.kind, cf.name)
+		}
+	}
+	return
+}
+
+// resolveConflicts - Applies the configured strategy to each app
+// and service that already exists at the destination
+func (c *CopyCommand) resolveConflicts() (err error) {
+
+	for i, cf := range c.conflicts {
+		switch cf.action {
+		case ConflictSkip:
+			c.conflicts[i].result = "kept existing"
+		case ConflictReplace:
+			if cf.kind == "app" {
+				err = c.deleteDestApplication(cf.app)
+			}
+			c.conflicts[i].result = "replaced"
+		case ConflictRename:
+			newName := fmt.Sprintf("%s-%s", cf.name, time.Now().Format("20060102150405"))
+			if cf.kind == "app" {
+				err = c.renameDestApplication(cf.app, newName)
+			} else {
+				err = c.renameDestService(cf.service, newName)
+			}
+			c.conflicts[i].result = fmt.Sprintf("existing renamed to %s", newName)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// isServiceCopied - Returns whether the given source service instance
+// is bound to one of the applications to be copied
+func (c *CopyCommand) isServiceCopied(serviceInstance models.ServiceInstance) bool {
+	for _, a := range serviceInstance.ApplicationNames {
+		for _, n := range c.o.SourceAppNames {
+			if a == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bindKeptServices - Binds the copied apps to the existing services
+// kept at the destination in place of the skipped source services
+func (c *CopyCommand) bindKeptServices() (err error) {
+
+	var app models.Application
+
+	for _, cf := range c.conflicts {
+		if cf.kind != "service" || cf.action != ConflictSkip {
+			continue
+		}
+		for _, name := range cf.boundApps {
+			if !containsString(c.o.SourceAppNames, name) {
+				continue
+			}
+			if app, err = c.destCCSession.Applications().Read(name); err != nil {
+				return
+			}
+			c.logger.UI.Say("Binding service %s to app %s...",
+				terminal.EntityNameColor(cf.name), terminal.EntityNameColor(name))
+			if err = c.destCCSession.ServiceBindings().Create(cf.service.GUID, app.GUID, nil); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (c *CopyCommand) deleteDestApplication(app models.Application) error {
+	c.logger.UI.Say("Deleting existing app %s...", terminal.EntityNameColor(app.Name))
+	return c.destCCSession.Applications().Delete(app.GUID)
+}
+
+func (c *CopyCommand) renameDestApplication(app models.Application, newName string) (err error) {
+	c.logger.UI.Say("Renaming existing app %s to %s...",
+		terminal.EntityNameColor(app.Name), terminal.EntityNameColor(newName))
+	_, err = c.destCCSession.Applications().Update(app.GUID, models.AppParams{Name: &newName})
+	return
+}
+
+func (c *CopyCommand) renameDestService(service models.ServiceInstance, newName string) error {
+	c.logger.UI.Say("Renaming existing service %s to %s...",
+		terminal.EntityNameColor(service.Name), terminal.EntityNameColor(newName))
+	return c.destCCSession.Services().RenameService(service, newName)
+}
+
+// printConflicts - Outputs the action taken for each conflicting
+// app and service and, once the copy is done, the result
+func (c *CopyCommand) printConflicts(title string, withResult bool) {
+
+	if len(c.conflicts) == 0 {
+		return
+	}
+
+	c.logger.UI.Say("")
+	c.logger.UI.Say(title)
+	c.logger.UI.Say("")
+
+	headers := []string{"type", "name", "on conflict"}
+	if withResult {
+		headers = append(headers, "result")
+	}
+	table := c.logger.UI.Table(headers)
+	for _, cf := range c.conflicts {
+		if withResult {
+			table.Add(cf.kind, cf.name, cf.action, cf.result)
+		} else {
+			table.Add(cf.kind, cf.name, cf.action)
+		}
+	}
+	table.Print()
+	c.logger.UI.Say("")
+}
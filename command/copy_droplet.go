@@ -0,0 +1,400 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	cferrors "code.cloudfoundry.org/cli/cf/errors"
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// defaultCacheSize - The size limit of the droplet cache in bytes
+const defaultCacheSize int64 = 5 * 1024 * 1024 * 1024
+
+// copiesDropletsDirectly - Droplets copied within the same Cloud
+// Foundry target are copied by the Cloud Controller so the bits
+// never leave the foundation. Droplets copied across targets are
+// streamed from the source to the destination or uploaded from the
+// cache if requested. Otherwise they are downloaded to local disk
+// and uploaded by the applications manager.
+func (c *CopyCommand) copiesDropletsDirectly() bool {
+	return c.o.CopyAsDroplet && (c.o.SourceTarget == c.o.DestTarget || c.o.StreamDroplets || c.o.CacheDir != "")
+}
+
+// copyApplicationsDirectly - Creates each application in the
+// destination space with the settings of its source, or updates it
+// if it was copied before, transfers the source droplet to it, maps
+// its routes and binds the copied services it was bound to at the
+// source. The applications are left stopped to be started once all
+// of them are copied.
+func (c *CopyCommand) copyApplicationsDirectly() (err error) {
+
+	var (
+		srcClient    *helpers.CCClient
+		destClient   *helpers.CCClient
+		srcServices  []models.ServiceInstance
+		destServices []models.ServiceInstance
+	)
+
+	if srcClient, err = c.newCCClient(c.o.SourceTarget); err != nil {
+		return
+	}
+	if destClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+		return
+	}
+	if srcServices, err = c.srcCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	if destServices, err = c.destCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+
+	for _, name := range c.o.SourceAppNames {
+		if err = c.copyApplicationDirectly(srcClient, destClient, name, srcServices, destServices); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *CopyCommand) copyApplicationDirectly(
+	srcClient, destClient *helpers.CCClient,
+	name string,
+	srcServices, destServices []models.ServiceInstance) (err error) {
+
+	var (
+		srcApp, destApp models.Application
+		boundServices   map[string]bool
+	)
+
+	transferStep := "transfer droplet"
+	if c.o.SourceTarget == c.o.DestTarget {
+		transferStep = "copy droplet"
+	}
+
+	if srcApp, err = c.srcCCSession.Applications().Read(name); err != nil {
+		return
+	}
+
+	stopped := "stopped"
+	params := models.AppParams{
+		Name:            &srcApp.Name,
+		SpaceGUID:       &c.destSpace.GUID,
+		Command:         &srcApp.Command,
+		BuildpackURL:    &srcApp.BuildpackURL,
+		InstanceCount:   &srcApp.InstanceCount,
+		Memory:          &srcApp.Memory,
+		DiskQuota:       &srcApp.DiskQuota,
+		EnvironmentVars: &srcApp.EnvironmentVars,
+		State:           &stopped,
+	}
+	if srcApp.HealthCheckTimeout > 0 {
+		params.HealthCheckTimeout = &srcApp.HealthCheckTimeout
+	}
+	if srcApp.HealthCheckType != "" {
+		params.HealthCheckType = &srcApp.HealthCheckType
+	}
+	if srcApp.HealthCheckHTTPEndpoint != "" {
+		params.HealthCheckHTTPEndpoint = &srcApp.HealthCheckHTTPEndpoint
+	}
+	if destApp, err = c.existingDestApplication(name); err != nil {
+		return
+	}
+	if destApp.GUID != "" {
+		// An app copied before is updated in place and given the new
+		// droplet as it was by the applications manager
+		c.prepareDestApplication(&params)
+		params.SpaceGUID = nil
+		if destApp, err = c.destCCSession.Applications().Update(destApp.GUID, params); err != nil {
+			return
+		}
+	} else if destApp, err = c.destCCSession.Applications().Create(params); err != nil {
+		return
+	}
+	if err = c.copyLifecycle(srcClient, destClient, srcApp.GUID, destApp.GUID); err != nil {
+		return
+	}
+
+	c.beginAppStep(name, transferStep)
+	if c.o.SourceTarget == c.o.DestTarget {
+		err = c.copyDropletServerSide(destClient, srcApp.GUID, destApp.GUID)
+	} else {
+		err = c.streamDroplet(srcClient, destClient, name, srcApp.GUID, destApp.GUID)
+	}
+	if err != nil {
+		return
+	}
+
+	// The routes of an intermediate copy stay with the app it is rolled out to
+	if len(srcApp.Routes) > 0 && !c.isRollingCopy(name) {
+		c.beginAppStep(name, "map routes")
+		for _, r := range srcApp.Routes {
+			if err = c.mapDestRoute(r, destApp); err != nil {
+				return
+			}
+		}
+	}
+
+	if boundServices, err = c.serviceBindings(destClient, destApp.GUID); err != nil {
+		return
+	}
+	for _, s := range srcServices {
+		if !containsString(s.ApplicationNames, name) {
+			continue
+		}
+		for _, d := range destServices {
+			if d.Name == s.Name {
+				if boundServices[d.GUID] {
+					break
+				}
+				c.logger.UI.Say("Binding service %s to app %s...",
+					terminal.EntityNameColor(d.Name), terminal.EntityNameColor(name))
+				if err = c.destCCSession.ServiceBindings().Create(d.GUID, destApp.GUID, nil); err != nil {
+					return
+				}
+				break
+			}
+		}
+	}
+	return
+}
+
+// existingDestApplication - Returns the destination application the
+// application with the given name is copied to if it exists. A copy
+// which replaces an application blue-green is always a new application.
+func (c *CopyCommand) existingDestApplication(name string) (app models.Application, err error) {
+
+	if c.blueGreenName(name) != name {
+		return
+	}
+	if app, err = c.destCCSession.Applications().Read(name); err != nil {
+		if _, ok := err.(*cferrors.ModelNotFoundError); ok {
+			return models.Application{}, nil
+		}
+	}
+	return
+}
+
+type v3Lifecycle struct {
+	Type string `json:"type"`
+	Data struct {
+		Buildpacks []string `json:"buildpacks"`
+		Stack      string   `json:"stack,omitempty"`
+	} `json:"data"`
+}
+
+// copyLifecycle - Gives the destination application the buildpacks and
+// the stack of the source application. The stack is given by name as
+// stack GUIDs differ between targets. Only buildpack apps have them.
+func (c *CopyCommand) copyLifecycle(srcClient, destClient *helpers.CCClient, srcAppGUID, destAppGUID string) (err error) {
+
+	app := struct {
+		Lifecycle v3Lifecycle `json:"lifecycle"`
+	}{}
+
+	if err = srcClient.Do("GET", "/v3/apps/"+srcAppGUID, nil, &app); err != nil {
+		return
+	}
+	if app.Lifecycle.Type != "buildpack" {
+		return
+	}
+	if app.Lifecycle.Data.Buildpacks == nil {
+		app.Lifecycle.Data.Buildpacks = []string{}
+	}
+	return destClient.Do("PATCH", "/v3/apps/"+destAppGUID, app, nil)
+}
+
+// copyDropletServerSide - Copies the current droplet of the source
+// application to the destination application using the v3 API
+func (c *CopyCommand) copyDropletServerSide(ccClient *helpers.CCClient, srcAppGUID, destAppGUID string) (err error) {
+
+	var droplet v3Droplet
+
+	if err = ccClient.Do("GET", fmt.Sprintf("/v3/apps/%s/droplets/current", srcAppGUID), nil, &droplet); err != nil {
+		return
+	}
+	if droplet, err = c.copyDroplet(ccClient, droplet.GUID, destAppGUID); err != nil {
+		return
+	}
+
+	currentDroplet := struct {
+		Data struct {
+			GUID string `json:"guid"`
+		} `json:"data"`
+	}{}
+	currentDroplet.Data.GUID = droplet.GUID
+	return ccClient.Do("PATCH", fmt.Sprintf("/v3/apps/%s/relationships/current_droplet", destAppGUID), currentDroplet, nil)
+}
+
+// streamDroplet - Streams the droplet of the source application to the
+// destination application and verifies the checksum of the uploaded
+// droplet against the checksum of the bits that were streamed. When a
+// cache is used the droplet is uploaded from the cache instead.
+func (c *CopyCommand) streamDroplet(srcClient, destClient *helpers.CCClient, name, srcAppGUID, destAppGUID string) (err error) {
+
+	var (
+		checksums helpers.DropletChecksums
+		droplet   v3Droplet
+	)
+
+	if err = c.retry.Do("transfer droplet of app "+srcAppGUID, func() (err error) {
+		if c.cache != nil {
+			checksums, err = c.uploadCachedDroplet(srcClient, destClient, name, srcAppGUID, destAppGUID)
+		} else {
+			checksums, err = helpers.StreamDroplet(srcClient, srcAppGUID, destClient, destAppGUID, c.startTimeout(), c.progress.For(name))
+		}
+		return
+	}); err != nil {
+		return
+	}
+	c.logger.DebugMessage("Transferred droplet => %# v", checksums)
+
+	if err = destClient.Do("GET", fmt.Sprintf("/v3/apps/%s/droplets/current", destAppGUID), nil, &droplet); err != nil {
+		return
+	}
+	if !checksums.Matches(droplet.Checksum.Type, droplet.Checksum.Value) {
+		return fmt.Errorf("The %s checksum '%s' of the uploaded droplet does not match the checksum of the streamed bits.",
+			droplet.Checksum.Type, droplet.Checksum.Value)
+	}
+	return
+}
+
+// uploadCachedDroplet - Uploads the droplet of the source application
+// from the cache. Droplets that are not cached yet are downloaded to
+// the cache first and are only kept if their checksum matches the
+// checksum reported by the source.
+func (c *CopyCommand) uploadCachedDroplet(
+	srcClient, destClient *helpers.CCClient,
+	name, srcAppGUID, destAppGUID string) (checksums helpers.DropletChecksums, err error) {
+
+	var (
+		droplet v3Droplet
+		file    *os.File
+		size    int64
+		ok      bool
+	)
+
+	if err = srcClient.Do("GET", fmt.Sprintf("/v3/apps/%s/droplets/current", srcAppGUID), nil, &droplet); err != nil {
+		return
+	}
+	if droplet.Checksum.Value == "" {
+		c.logger.UI.Warn("The source does not report droplet checksums so the droplet will not be cached.")
+		return helpers.StreamDroplet(srcClient, srcAppGUID, destClient, destAppGUID, c.startTimeout(), c.progress.For(name))
+	}
+
+	key := helpers.CacheKey(droplet.Checksum.Type, droplet.Checksum.Value)
+	file, size, ok = c.cache.Get(key)
+	if ok {
+		c.logger.UI.Say("  using cached droplet %s", terminal.EntityNameColor(key))
+	} else {
+		c.logger.UI.Say("  downloading droplet %s to cache", terminal.EntityNameColor(key))
+		if err = c.cache.Put(key, func(w io.Writer) error {
+			downloaded, err := helpers.DownloadDroplet(srcClient, srcAppGUID, w, c.progress.For(name))
+			if err == nil && !downloaded.Matches(droplet.Checksum.Type, droplet.Checksum.Value) {
+				err = fmt.Errorf("The downloaded droplet does not match its %s checksum '%s'.",
+					droplet.Checksum.Type, droplet.Checksum.Value)
+			}
+			return err
+		}); err != nil {
+			return
+		}
+		if file, size, ok = c.cache.Get(key); !ok {
+			err = fmt.Errorf("The droplet '%s' could not be read from the cache.", key)
+			return
+		}
+	}
+	defer file.Close()
+
+	if err = helpers.UploadDropletFile(destClient, destAppGUID, file, size, c.startTimeout(), c.progress.For(name)); err != nil {
+		return
+	}
+	checksums = helpers.DropletChecksums{Size: size}
+	switch droplet.Checksum.Type {
+	case "sha1":
+		checksums.SHA1 = droplet.Checksum.Value
+	case "sha256":
+		checksums.SHA256 = droplet.Checksum.Value
+	}
+	return
+}
+
+// mapDestRoute - Maps the route of a destination application that
+// corresponds to the given source route. The host is rendered from
+// the '--host-format' template and the domain is replaced with the
+// '--domain' option when they are provided.
+func (c *CopyCommand) mapDestRoute(srcRoute models.RouteSummary, destApp models.Application) (err error) {
+
+	host := srcRoute.Host
+	if c.o.AppHostFormat != "" && host != "" {
+		if host, err = c.formatHost(host); err != nil {
+			return
+		}
+	}
+	domainName := srcRoute.Domain.Name
+	if c.o.AppRouteDomain != "" {
+		domainName = c.o.AppRouteDomain
+	}
+	return c.bindDestRoute(host, domainName, srcRoute, destApp)
+}
+
+// bindDestRoute - Maps the route with the given host and domain and the
+// path and port of the given source route to a destination application
+// creating the route in the destination space if it does not exist
+func (c *CopyCommand) bindDestRoute(host, domainName string, srcRoute models.RouteSummary, destApp models.Application) (err error) {
+
+	var (
+		domain models.DomainFields
+		route  models.Route
+	)
+
+	if domain, err = c.destCCSession.Domains().FindByNameInOrg(domainName, c.destOrg.GUID); err != nil {
+		return
+	}
+
+	if route, err = c.destCCSession.Routes().Find(host, domain, srcRoute.Path, srcRoute.Port); err != nil {
+		if route, err = c.destCCSession.Routes().Create(host, domain, srcRoute.Path, srcRoute.Port, false); err != nil {
+			return
+		}
+	} else if route.Space.GUID != c.destSpace.GUID {
+		// Routes are unique within a target so a route found
+		// in another space, such as that of the source, cannot
+		// be mapped to the destination application
+		return fmt.Errorf("The route '%s' belongs to another space. Use --host-format or --domain to give the copied apps their own routes.", route.URL())
+	}
+	c.logger.UI.Say("Mapping route %s to app %s...",
+		terminal.EntityNameColor(route.URL()), terminal.EntityNameColor(destApp.Name))
+	return c.destCCSession.Routes().Bind(route.GUID, destApp.GUID)
+}
+
+// formatHost - Renders the host format template for the given host
+func (c *CopyCommand) formatHost(host string) (string, error) {
+
+	t, err := template.New("host").Parse(c.o.AppHostFormat)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err = t.Execute(&b, map[string]string{
+		"host":  host,
+		"space": c.destSpace.Name,
+		"org":   c.destOrg.Name,
+	}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, s := range list {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// Destination - One of the destinations a space is copied to
+type Destination struct {
+	Target string
+	Org    string
+	Space  string
+}
+
+// String - Describes the destination as it is given with --to
+func (d Destination) String() string {
+	parts := []string{}
+	for _, p := range []string{d.Target, d.Org, d.Space} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// parseDestinations - Parses destinations given as SPACE, ORG/SPACE
+// or TARGET/ORG/SPACE. Invalid destinations are reported using the
+// given function.
+func parseDestinations(values []string, fail func(format string, v ...interface{})) []Destination {
+
+	destinations := []Destination{}
+	for _, value := range values {
+
+		var d Destination
+
+		parts := strings.Split(value, "/")
+		switch len(parts) {
+		case 1:
+			d.Space = parts[0]
+		case 2:
+			d.Org, d.Space = parts[0], parts[1]
+		case 3:
+			d.Target, d.Org, d.Space = parts[0], parts[1], parts[2]
+		}
+		if len(parts) > 3 || d.Space == "" || (len(parts) > 1 && d.Org == "") || (len(parts) > 2 && d.Target == "") {
+			fail("invalid destination '%s'. A destination is given as [[TARGET/]ORG/]SPACE.", value)
+			continue
+		}
+
+		duplicate := false
+		for _, e := range destinations {
+			duplicate = duplicate || e == d
+		}
+		if duplicate {
+			fail("The destination '%s' is given more than once.", value)
+			continue
+		}
+		destinations = append(destinations, d)
+	}
+	return destinations
+}
+
+// fanOut - Copies the source space to each destination of the given
+// options. The content of the source applications and the droplets
+// copied to another target are cached so they are only downloaded
+// once. A copy that fails does not stop the copies to the destinations
+// after it.
+func (c *CopyCommand) fanOut(options *CopyOptions) int {
+
+	fo := *options
+	o := &fo
+
+	if o.CopyAsDroplet && !o.StreamDroplets && o.CacheDir == "" && len(o.Destinations) > 1 {
+		dir, err := ioutil.TempDir("", "cf-copy-droplets")
+		if err != nil {
+			c.logger.UI.Failed("Error creating droplet cache: %s", err.Error())
+			return ExitPreflightFailure
+		}
+		defer os.RemoveAll(dir)
+
+		c.logger.DebugMessage("Caching droplets copied to %d destinations in '%s'", len(o.Destinations), dir)
+		o.CacheDir = dir
+		o.CacheSize = 0
+	}
+
+	if len(o.Destinations) > 1 {
+		dir, err := ioutil.TempDir("", "cf-copy-downloads")
+		if err != nil {
+			c.logger.UI.Failed("Error creating download cache: %s", err.Error())
+			return ExitPreflightFailure
+		}
+		defer os.RemoveAll(dir)
+
+		c.downloads = helpers.NewDownloadCache(dir)
+		defer func() { c.downloads = nil }()
+	}
+
+	results := []copyResult{}
+	for _, d := range o.Destinations {
+
+		do := *o
+		do.Destinations = nil
+		do.DestTarget = d.Target
+		do.DestOrg = d.Org
+		do.DestSpace = d.Space
+		do.SourceAppNames = append([]string(nil), o.SourceAppNames...)
+		do.ServiceInstancesToCopyAsUPS = append([]string(nil), o.ServiceInstancesToCopyAsUPS...)
+
+		c.logger.UI.Say("")
+		c.logger.UI.Say("Copying to %s...", terminal.EntityNameColor(d.String()))
+
+		start := time.Now()
+		result := copyResult{name: d.String()}
+		result.exitCode = c.copy(&do)
+		result.duration = time.Since(start)
+		results = append(results, result)
+
+		if o.Interactive && len(do.SourceAppNames) > 0 {
+			// The apps and services selected are copied
+			// to the remaining destinations as well
+			o.Interactive = false
+			o.SourceAppNames = do.SourceAppNames
+			o.ServiceInstancesToCopyAsUPS = do.ServiceInstancesToCopyAsUPS
+		}
+	}
+	return reportResults(c.logger.UI, results, "copies to the destinations")
+}
@@ -0,0 +1,103 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// maxSelectionAttempts - How many times a selection is asked
+// for again when the answer given cannot be parsed
+const maxSelectionAttempts = 3
+
+// selectInteractively - Lists the source apps and the services bound to
+// the apps picked from them and lets the user pick the apps to copy and
+// the services to copy as user provided services instead of natively
+func (c *CopyCommand) selectInteractively() (err error) {
+
+	var (
+		indexes  []int
+		services []models.ServiceInstance
+	)
+
+	if !helpers.IsTerminal(os.Stdin) {
+		return errors.New("The --interactive option can only be used when the input is a terminal.")
+	}
+	if len(c.srcApps) == 0 {
+		return errors.New("There are no apps in the source space to select from.")
+	}
+
+	c.logger.UI.Say("")
+	table := c.logger.UI.Table([]string{"", "name", "state", "instances", "memory"})
+	for i, a := range c.srcApps {
+		table.Add(strconv.Itoa(i+1), a.Name, a.State,
+			fmt.Sprintf("%d/%d", a.RunningInstances, a.InstanceCount), fmt.Sprintf("%dM", a.Memory))
+	}
+	table.Print()
+	c.logger.UI.Say("")
+
+	if indexes, err = c.askSelection("Select the apps to copy (i.e. 1,3-5 or all)", len(c.srcApps)); err != nil {
+		return
+	}
+	if len(indexes) == 0 {
+		return errors.New("No apps were selected.")
+	}
+	c.o.SourceAppNames = []string{}
+	for _, i := range indexes {
+		c.o.SourceAppNames = append(c.o.SourceAppNames, c.srcApps[i].Name)
+	}
+
+	if services, err = c.srcCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	bound := []models.ServiceInstance{}
+	for _, s := range services {
+		if c.isServiceCopied(s) {
+			bound = append(bound, s)
+		}
+	}
+	if len(bound) == 0 {
+		return
+	}
+
+	c.logger.UI.Say("")
+	c.logger.UI.Say("The following services are bound to the selected apps and will be copied:")
+	c.logger.UI.Say("")
+	table = c.logger.UI.Table([]string{"", "name", "service", "plan", "bound apps"})
+	for i, s := range bound {
+		service := s.ServiceOffering.Label
+		if s.IsUserProvided() {
+			service = "user-provided"
+		}
+		table.Add(strconv.Itoa(i+1), s.Name, service, s.ServicePlan.Name, strings.Join(s.ApplicationNames, ", "))
+	}
+	table.Print()
+	c.logger.UI.Say("")
+
+	if indexes, err = c.askSelection("Select the services to copy as user provided services (i.e. 1,3-5, all or none)", len(bound)); err != nil {
+		return
+	}
+	c.o.ServiceInstancesToCopyAsUPS = []string{}
+	for _, i := range indexes {
+		c.o.ServiceInstancesToCopyAsUPS = append(c.o.ServiceInstancesToCopyAsUPS, bound[i].Name)
+	}
+	return
+}
+
+// askSelection - Asks for items of a numbered list until a
+// valid selection is given or the attempts are used up
+func (c *CopyCommand) askSelection(prompt string, count int) (indexes []int, err error) {
+	for attempt := 0; attempt < maxSelectionAttempts; attempt++ {
+		if indexes, err = helpers.ParseSelection(c.logger.UI.Ask(prompt), count); err == nil {
+			return
+		}
+		c.logger.UI.Say(terminal.FailureColor(err.Error()))
+	}
+	return nil, fmt.Errorf("No valid selection was made: %s", err.Error())
+}
@@ -0,0 +1,267 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// routePollInterval - How often routes that do not respond are checked again
+const routePollInterval = 5 * time.Second
+
+// decommissionSource - Completes a move once the copied applications are
+// healthy. When the routes of the started copies respond the routes of
+// the source applications are moved to the copies if requested and the
+// source applications are stopped or deleted.
+func (c *CopyCommand) decommissionSource() (err error) {
+
+	var destApps []models.Application
+
+	if destApps, err = c.destCCSession.AppSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	copies := make(map[string]models.Application)
+	started := make(map[string]models.Application)
+	for _, a := range destApps {
+		if containsString(c.o.SourceAppNames, a.Name) {
+			copies[a.Name] = a
+			if c.startsApp(a.Name) {
+				started[a.Name] = a
+			}
+		}
+	}
+	// The routes of copies of apps stopped at the source do not respond
+	if err = c.waitForRoutes(started); err != nil {
+		return
+	}
+
+	for _, name := range c.o.SourceAppNames {
+		srcApp, ok := c.srcApp(name)
+		if !ok {
+			continue
+		}
+		if c.o.RemapRoutes {
+			if err = c.moveRoutes(srcApp, copies[name]); err != nil {
+				return
+			}
+		}
+		if c.o.DeleteSource {
+			c.logger.UI.Say("Deleting source app %s...", terminal.EntityNameColor(name))
+			if err = c.srcCCSession.Applications().Delete(srcApp.GUID); err != nil {
+				return
+			}
+		} else if !strings.EqualFold(srcApp.State, "stopped") {
+			stopped := "stopped"
+			c.logger.UI.Say("Stopping source app %s...", terminal.EntityNameColor(name))
+			if _, err = c.srcCCSession.Applications().Update(srcApp.GUID, models.AppParams{State: &stopped}); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// waitForRoutes - Waits for the HTTP routes of the given applications
+// to respond until the start timeout expires
+func (c *CopyCommand) waitForRoutes(apps map[string]models.Application) (err error) {
+
+	pending := []string{}
+	for _, a := range apps {
+		for _, r := range a.Routes {
+			if r.Port == 0 {
+				pending = append(pending, r.URL())
+			}
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	c.logger.UI.Say("Waiting for routes of copied apps to respond...")
+	return c.waitForURLs(pending)
+}
+
+// waitForURLs - Waits for the given routes to respond until the
+// start timeout expires
+func (c *CopyCommand) waitForURLs(pending []string) (err error) {
+
+	sslDisabled, _ := c.cli.IsSSLDisabled()
+	checker := helpers.NewRouteChecker(sslDisabled)
+
+	deadline := time.Now().Add(c.startTimeout())
+	for {
+		failing := []string{}
+		for _, route := range pending {
+			if err = checker.Check(route); err != nil {
+				c.logger.DebugMessage("Route '%s' does not respond: %s", route, err.Error())
+				failing = append(failing, route)
+			}
+		}
+		if len(failing) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("The route %s of a copied app does not respond: %s", failing[0], err.Error())
+		}
+		pending = failing
+		time.Sleep(routePollInterval)
+	}
+}
+
+// v3RouteDestinations - The destinations of a route in the v3 API
+type v3RouteDestinations struct {
+	Destinations []struct {
+		GUID string `json:"guid"`
+		App  struct {
+			GUID string `json:"guid"`
+		} `json:"app"`
+	} `json:"destinations"`
+}
+
+// moveRoutes - Moves the routes of a source application to its copy. As
+// routes belong to a space a route is first shared with the destination
+// space and mapped to the copy. Only once the copy is verified to be a
+// destination of the route and, if it was started, to have all of its
+// instances running is the route unmapped from the source application
+// and its ownership transferred to the destination space, so a route
+// that cannot be moved stays with the source app. Sharing routes
+// requires the 'route_sharing' feature flag to be enabled.
+func (c *CopyCommand) moveRoutes(srcApp, destApp models.Application) (err error) {
+
+	var ccClient *helpers.CCClient
+
+	if ccClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+		return
+	}
+
+	for _, r := range srcApp.Routes {
+		mapped := false
+		for _, dr := range destApp.Routes {
+			if dr.URL() == r.URL() {
+				mapped = true
+				break
+			}
+		}
+		if mapped {
+			continue
+		}
+
+		c.logger.UI.Say("Moving route %s from source app %s...",
+			terminal.EntityNameColor(r.URL()), terminal.EntityNameColor(srcApp.Name))
+		if err = c.mapRouteToCopy(ccClient, r, destApp); err != nil {
+			return fmt.Errorf("The route %s could not be mapped to the copy of app '%s' and is still mapped to the source app: %s",
+				r.URL(), srcApp.Name, err.Error())
+		}
+		if err = c.srcCCSession.Routes().Unbind(r.GUID, srcApp.GUID); err != nil {
+			return
+		}
+		transfer := map[string]interface{}{
+			"data": map[string]string{"guid": c.destSpace.GUID},
+		}
+		if err = ccClient.Do("PATCH", fmt.Sprintf("/v3/routes/%s/transfer_owner", r.GUID), transfer, nil); err != nil {
+			return
+		}
+		if err = ccClient.Do("DELETE", fmt.Sprintf("/v3/routes/%s/relationships/shared_spaces/%s", r.GUID, c.srcSpace.GUID), nil, nil); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// mapRouteToCopy - Shares the given source route with the destination
+// space and maps it to the copied application. The mapping is removed
+// again if the copy is not a destination of the route or, as long as
+// the route is still mapped to the source application as well and a
+// response from it would not show that the copy serves it, if the
+// instances of a started copy are not all running.
+func (c *CopyCommand) mapRouteToCopy(ccClient *helpers.CCClient, r models.RouteSummary, destApp models.Application) (err error) {
+
+	var (
+		destinations  v3RouteDestinations
+		destinationID string
+	)
+
+	share := map[string]interface{}{
+		"data": []map[string]string{{"guid": c.destSpace.GUID}},
+	}
+	if err = ccClient.Do("POST", fmt.Sprintf("/v3/routes/%s/relationships/shared_spaces", r.GUID), share, nil); err != nil {
+		return
+	}
+	destination := map[string]interface{}{
+		"destinations": []map[string]interface{}{
+			{"app": map[string]string{"guid": destApp.GUID}},
+		},
+	}
+	if err = ccClient.Do("POST", fmt.Sprintf("/v3/routes/%s/destinations", r.GUID), destination, &destinations); err != nil {
+		return
+	}
+	for _, d := range destinations.Destinations {
+		if d.App.GUID == destApp.GUID {
+			destinationID = d.GUID
+		}
+	}
+	if destinationID == "" {
+		err = fmt.Errorf("The app '%s' is not a destination of the route.", destApp.Name)
+	} else if c.startsApp(destApp.Name) {
+		err = c.checkCopyHealth(destApp.Name)
+	}
+	if err != nil {
+		if destinationID != "" {
+			ccClient.Do("DELETE", fmt.Sprintf("/v3/routes/%s/destinations/%s", r.GUID, destinationID), nil, nil)
+		}
+		ccClient.Do("DELETE", fmt.Sprintf("/v3/routes/%s/relationships/shared_spaces/%s", r.GUID, c.destSpace.GUID), nil, nil)
+	}
+	return
+}
+
+// checkCopyHealth - Fails unless all instances of the copied
+// application with the given name are running
+func (c *CopyCommand) checkCopyHealth(name string) (err error) {
+
+	var health []appHealth
+
+	if health, err = c.waitForApplications([]string{name}, c.startTimeout()); err != nil {
+		return
+	}
+	if len(health) == 0 || !health[0].isHealthy() {
+		return fmt.Errorf("Not all instances of the copied app '%s' are running.", name)
+	}
+	return
+}
+
+// checkRouteSharing - Fails if the 'route_sharing' feature flag
+// that routes are moved with is disabled at the destination
+func (c *CopyCommand) checkRouteSharing() (err error) {
+
+	var (
+		ccClient *helpers.CCClient
+		flag     struct {
+			Enabled bool `json:"enabled"`
+		}
+	)
+
+	if ccClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+		return
+	}
+	if err = ccClient.Do("GET", "/v3/feature_flags/route_sharing", nil, &flag); err != nil {
+		return
+	}
+	if !flag.Enabled {
+		return errors.New("Routes can only be moved when the 'route_sharing' feature flag is enabled. It can be enabled by an admin with 'cf enable-feature-flag route_sharing'.")
+	}
+	return
+}
+
+// srcApp - Returns the source application with the given name
+func (c *CopyCommand) srcApp(name string) (models.Application, bool) {
+	for _, a := range c.srcApps {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return models.Application{}, false
+}
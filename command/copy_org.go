@@ -0,0 +1,280 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// OrgCopy - A copy of the spaces of an org to another org. The private
+// domains, space quotas and roles of the org are copied and missing
+// spaces are created before the spaces are copied one by one.
+type OrgCopy struct {
+	// IncludeSpaces - Patterns of the names of the spaces to
+	// copy. All spaces are copied if no patterns are given.
+	IncludeSpaces []string
+	// ExcludeSpaces - Patterns of the names of the spaces not to copy
+	ExcludeSpaces []string
+}
+
+// parseOrgCopy - Sets the destination org and target of an org copy
+// from the positional arguments and the space filters from the options.
+// Invalid arguments are reported using the given function.
+func parseOrgCopy(o *CopyOptions, a *copyArgs, positionals []string, fail func(format string, v ...interface{})) {
+
+	for i, arg := range positionals {
+		switch i {
+		case 0:
+			o.DestOrg = arg
+		case 1:
+			o.DestTarget = arg
+		default:
+			fail("Invalid positional argument '%s'.", arg)
+		}
+	}
+	if o.DestOrg == "" {
+		o.DestOrg = a.positional("org")
+	}
+	if o.DestTarget == "" {
+		o.DestTarget = a.positional("target")
+	}
+	if o.DestOrg == "" {
+		fail("The destination org must be provided.")
+	}
+
+	// Each space is copied as a whole
+	for _, name := range []string{"source-space", "apps", "ups", "interactive", "to"} {
+		if a.IsSet(name) {
+			fail("The --%s option cannot be used when copying an org.", name)
+		}
+	}
+
+	o.OrgCopy = &OrgCopy{}
+	if a.IsSet("spaces") {
+		o.OrgCopy.IncludeSpaces = strings.Split(a.String("spaces"), ",")
+	}
+	if a.IsSet("exclude-spaces") {
+		o.OrgCopy.ExcludeSpaces = strings.Split(a.String("exclude-spaces"), ",")
+	}
+	for _, p := range append(o.OrgCopy.IncludeSpaces, o.OrgCopy.ExcludeSpaces...) {
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			fail("invalid space pattern '%s'", p)
+		}
+	}
+}
+
+// includesSpace - Returns whether the space with the given name is copied
+func (oc *OrgCopy) includesSpace(name string) bool {
+
+	for _, p := range oc.ExcludeSpaces {
+		if matched, _ := path.Match(p, name); matched {
+			return false
+		}
+	}
+	if len(oc.IncludeSpaces) == 0 {
+		return true
+	}
+	for _, p := range oc.IncludeSpaces {
+		if matched, _ := path.Match(p, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// copyOrg - Prepares the destination org and then copies each selected
+// space of the source org to the space with the same name in the
+// destination org. A space copy that fails does not stop the copies
+// of the spaces after it.
+func (c *CopyCommand) copyOrg(options *CopyOptions) int {
+
+	var (
+		err    error
+		spaces []v3Resource
+	)
+
+	oc := *options
+	o := &oc
+	c.o = o
+
+	if _, err = c.resolveTargets(); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitTargetError
+	}
+	if o.SourceOrg == "" {
+		if o.SourceOrg, err = c.targetedOrg(o.SourceTarget); err != nil {
+			c.logger.UI.Failed(err.Error())
+			return ExitTargetError
+		}
+	}
+	if o.SourceTarget == o.DestTarget && o.SourceOrg == o.DestOrg {
+		c.logger.UI.Failed("The source and destination are the same.")
+		return ExitUsageError
+	}
+	if o.Retries > 0 {
+		c.retry = helpers.NewRetryPolicy(o.Retries, c.logger.DebugMessage)
+	}
+
+	setup := &orgSetup{c: c}
+	if setup.src, err = c.newCCClient(o.SourceTarget); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitTargetError
+	}
+	if setup.dest, err = c.newCCClient(o.DestTarget); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitTargetError
+	}
+
+	if setup.srcOrg, err = findV3Resource(setup.src, "/v3/organizations?names="+url.QueryEscape(o.SourceOrg)); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitPreflightFailure
+	}
+	if setup.srcOrg == nil {
+		c.logger.UI.Failed("The source org '%s' does not exist.", o.SourceOrg)
+		return ExitPreflightFailure
+	}
+	if setup.destOrg, err = findV3Resource(setup.dest, "/v3/organizations?names="+url.QueryEscape(o.DestOrg)); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitDestinationNotFound
+	}
+	if setup.destOrg == nil {
+		c.logger.UI.Failed("The destination org '%s' does not exist.", o.DestOrg)
+		return ExitDestinationNotFound
+	}
+
+	if spaces, err = listV3Resources(setup.src, "/v3/spaces?organization_guids="+setup.srcOrg.GUID); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitPreflightFailure
+	}
+	for _, s := range spaces {
+		if o.OrgCopy.includesSpace(s.Name) {
+			setup.spaces = append(setup.spaces, s)
+		}
+	}
+	if len(setup.spaces) == 0 {
+		c.logger.UI.Failed("None of the spaces of org '%s' are selected to be copied.", o.SourceOrg)
+		return ExitPreflightFailure
+	}
+	if err = c.checkProtectedSpaces(setup.spaces); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitPreflightFailure
+	}
+
+	confirmed, err := c.confirmOrgCopy(setup.spaces)
+	if err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitNotConfirmed
+	}
+	if !confirmed {
+		c.logger.UI.Say("Copy cancelled.")
+		return ExitNotConfirmed
+	}
+
+	c.logger.UI.Say("Copying org %s / %s to %s / %s...",
+		terminal.EntityNameColor(o.SourceTarget), terminal.EntityNameColor(o.SourceOrg),
+		terminal.EntityNameColor(o.DestTarget), terminal.EntityNameColor(o.DestOrg))
+	if err = setup.run(); err != nil {
+		c.logger.UI.Failed(err.Error())
+		if setup.changed {
+			return ExitPartialCopy
+		}
+		return ExitPreflightFailure
+	}
+
+	results := []copyResult{}
+	for _, s := range setup.spaces {
+
+		so := *o
+		so.OrgCopy = nil
+		so.SourceSpace = s.Name
+		so.DestSpace = s.Name
+		so.SourceAppNames = nil
+		// The org copy has been confirmed as a whole
+		so.Force = true
+		// Each space has its own applications
+		c.ac = nil
+
+		c.logger.UI.Say("")
+		c.logger.UI.Say("Copying space %s...", terminal.EntityNameColor(s.Name))
+
+		start := time.Now()
+		result := copyResult{name: s.Name}
+		result.exitCode = c.copy(&so)
+		result.duration = time.Since(start)
+		results = append(results, result)
+	}
+	return reportResults(c.logger.UI, results, "space copies of the org")
+}
+
+// targetedOrg - Returns the name of the org the given target is targeted at
+func (c *CopyCommand) targetedOrg(target string) (string, error) {
+
+	sslDisabled, _ := c.cli.IsSSLDisabled()
+	session, err := c.sessionProvider.NewCfSessionFromFilepath(
+		c.targets.GetTargetConfigPath(target), sslDisabled, c.logger)
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	if !session.HasTarget() {
+		return "", fmt.Errorf("The target '%s' has no org targeted so a source org must be provided.", target)
+	}
+	return session.GetSessionOrg().Name, nil
+}
+
+// checkProtectedSpaces - Fails if any of the spaces copied to is
+// protected and copying to it has not been allowed with --allow-protected
+func (c *CopyCommand) checkProtectedSpaces(spaces []v3Resource) error {
+
+	if c.o.AllowProtected {
+		return nil
+	}
+	for _, s := range spaces {
+		for _, p := range c.o.ProtectedDestinations {
+			if p.Matches(c.o.DestTarget, c.o.DestOrg, s.Name) {
+				return fmt.Errorf("The destination space '%s' is protected by '%s'. Use --allow-protected to copy to it.",
+					s.Name, p.String())
+			}
+		}
+	}
+	return nil
+}
+
+// confirmOrgCopy - Shows the spaces that will be copied and asks for
+// confirmation before the destination org is changed. The copy is
+// confirmed without asking if --force is set.
+func (c *CopyCommand) confirmOrgCopy(spaces []v3Resource) (bool, error) {
+
+	if c.o.Force {
+		return true, nil
+	}
+	if !helpers.IsTerminal(os.Stdin) {
+		return false, errors.New("The copy cannot be confirmed as the input is not a terminal. Use --force to copy without confirmation.")
+	}
+
+	names := []string{}
+	for _, s := range spaces {
+		names = append(names, s.Name)
+	}
+
+	table := c.logger.UI.Table([]string{"", ""})
+	table.Add(terminal.HeaderColor("source"), fmt.Sprintf("target %s / org %s",
+		terminal.EntityNameColor(c.o.SourceTarget), terminal.EntityNameColor(c.o.SourceOrg)))
+	table.Add(terminal.HeaderColor("destination"), fmt.Sprintf("target %s / org %s",
+		terminal.EntityNameColor(c.o.DestTarget), terminal.EntityNameColor(c.o.DestOrg)))
+	table.Add(terminal.HeaderColor("spaces"), summaryList(names))
+	table.Add(terminal.HeaderColor("org setup"), "private domains, space quotas and roles")
+	table.Print()
+	c.logger.UI.Say("")
+
+	return c.logger.UI.Confirm(fmt.Sprintf("Really copy org %s to org %s?",
+		terminal.EntityNameColor(c.o.SourceOrg), terminal.EntityNameColor(c.o.DestOrg))), nil
+}
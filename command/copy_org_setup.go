@@ -0,0 +1,488 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// v3ListSize - The number of resources requested from v3
+// list endpoints which is the largest page size they allow
+const v3ListSize = 5000
+
+type v3Resource struct {
+	GUID string `json:"guid"`
+	Name string `json:"name"`
+}
+
+type v3GUID struct {
+	GUID string `json:"guid"`
+}
+
+type v3ToOne struct {
+	Data *v3GUID `json:"data"`
+}
+
+type v3ToMany struct {
+	Data []v3GUID `json:"data"`
+}
+
+type v3Domain struct {
+	v3Resource
+	Relationships struct {
+		Organization        v3ToOne  `json:"organization"`
+		SharedOrganizations v3ToMany `json:"shared_organizations"`
+	} `json:"relationships"`
+}
+
+type v3SpaceQuota struct {
+	v3Resource
+	Apps          json.RawMessage `json:"apps"`
+	Services      json.RawMessage `json:"services"`
+	Routes        json.RawMessage `json:"routes"`
+	Relationships struct {
+		Spaces v3ToMany `json:"spaces"`
+	} `json:"relationships"`
+}
+
+type v3Role struct {
+	Type          string `json:"type"`
+	Relationships struct {
+		User  v3ToOne `json:"user"`
+		Space v3ToOne `json:"space"`
+	} `json:"relationships"`
+}
+
+func (r v3Role) userGUID() string {
+	if r.Relationships.User.Data == nil {
+		return ""
+	}
+	return r.Relationships.User.Data.GUID
+}
+
+type v3User struct {
+	GUID     string `json:"guid"`
+	Username string `json:"username"`
+	Origin   string `json:"origin"`
+}
+
+// orgSetup - Prepares the destination org of an org copy with the
+// spaces, private domains, space quotas and roles of the source org
+type orgSetup struct {
+	c *CopyCommand
+
+	src  *helpers.CCClient
+	dest *helpers.CCClient
+
+	srcOrg  *v3Resource
+	destOrg *v3Resource
+
+	// spaces - The source spaces that are copied
+	spaces []v3Resource
+	// destSpaces - The GUIDs of the destination spaces
+	// keyed by the GUIDs of their source spaces
+	destSpaces map[string]string
+
+	// changed - Whether the destination org has been changed
+	changed bool
+	// noSpaceSupporter - Whether the space_supporter role is left out
+	// as a target does not know it
+	noSpaceSupporter bool
+}
+
+// run - Creates the missing spaces and then copies the private domains,
+// space quotas and roles of the org. Roles of users that cannot be
+// given a role at the destination are reported and skipped.
+func (s *orgSetup) run() (err error) {
+
+	if err = s.createSpaces(); err != nil {
+		return
+	}
+	if err = s.copyPrivateDomains(); err != nil {
+		return
+	}
+	if err = s.copySpaceQuotas(); err != nil {
+		return
+	}
+	return s.copyRoles()
+}
+
+// createSpaces - Creates the copied spaces that do not exist in the destination org
+func (s *orgSetup) createSpaces() (err error) {
+
+	var destSpaces []v3Resource
+
+	if destSpaces, err = listV3Resources(s.dest, "/v3/spaces?organization_guids="+s.destOrg.GUID); err != nil {
+		return
+	}
+	existing := make(map[string]string)
+	for _, ds := range destSpaces {
+		existing[ds.Name] = ds.GUID
+	}
+
+	s.destSpaces = make(map[string]string)
+	for _, space := range s.spaces {
+		if guid, ok := existing[space.Name]; ok {
+			s.destSpaces[space.GUID] = guid
+			continue
+		}
+
+		var created v3Resource
+
+		s.c.logger.UI.Say("Creating space %s...", terminal.EntityNameColor(space.Name))
+		request := map[string]interface{}{
+			"name": space.Name,
+			"relationships": map[string]interface{}{
+				"organization": v3ToOne{Data: &v3GUID{GUID: s.destOrg.GUID}},
+			},
+		}
+		if err = s.dest.Do("POST", "/v3/spaces", request, &created); err != nil {
+			return fmt.Errorf("The space '%s' could not be created: %s", space.Name, err.Error())
+		}
+		s.changed = true
+		s.destSpaces[space.GUID] = created.GUID
+	}
+	return
+}
+
+// copyPrivateDomains - Creates the private domains of the source org in
+// the destination org. A domain that already exists at the destination,
+// which is always the case when copying within a target, is shared with
+// the destination org instead.
+func (s *orgSetup) copyPrivateDomains() (err error) {
+
+	var domains []v3Domain
+
+	if err = listV3(s.src, "/v3/domains?owning_organization_guids="+s.srcOrg.GUID, &domains, nil); err != nil {
+		return
+	}
+	for _, d := range domains {
+
+		var existing []v3Domain
+
+		if err = listV3(s.dest, "/v3/domains?names="+url.QueryEscape(d.Name), &existing, nil); err != nil {
+			return
+		}
+		if len(existing) == 0 {
+			s.c.logger.UI.Say("Creating private domain %s...", terminal.EntityNameColor(d.Name))
+			request := map[string]interface{}{
+				"name": d.Name,
+				"relationships": map[string]interface{}{
+					"organization": v3ToOne{Data: &v3GUID{GUID: s.destOrg.GUID}},
+				},
+			}
+			if err = s.dest.Do("POST", "/v3/domains", request, nil); err != nil {
+				return fmt.Errorf("The private domain '%s' could not be created: %s", d.Name, err.Error())
+			}
+			s.changed = true
+			continue
+		}
+
+		ed := existing[0]
+		shared := ed.Relationships.Organization.Data != nil && ed.Relationships.Organization.Data.GUID == s.destOrg.GUID
+		for _, o := range ed.Relationships.SharedOrganizations.Data {
+			shared = shared || o.GUID == s.destOrg.GUID
+		}
+		if shared {
+			continue
+		}
+		s.c.logger.UI.Say("Sharing private domain %s...", terminal.EntityNameColor(d.Name))
+		request := v3ToMany{Data: []v3GUID{{GUID: s.destOrg.GUID}}}
+		if err = s.dest.Do("POST", fmt.Sprintf("/v3/domains/%s/relationships/shared_organizations", ed.GUID), request, nil); err != nil {
+			return fmt.Errorf("The private domain '%s' could not be shared with org '%s': %s", d.Name, s.destOrg.Name, err.Error())
+		}
+		s.changed = true
+	}
+	return
+}
+
+// copySpaceQuotas - Creates the space quotas of the source org that do
+// not exist in the destination org with the same limits and applies
+// them to the copies of the spaces they are applied to
+func (s *orgSetup) copySpaceQuotas() (err error) {
+
+	var srcQuotas, destQuotas []v3SpaceQuota
+
+	if err = listV3(s.src, "/v3/space_quotas?organization_guids="+s.srcOrg.GUID, &srcQuotas, nil); err != nil {
+		return
+	}
+	if err = listV3(s.dest, "/v3/space_quotas?organization_guids="+s.destOrg.GUID, &destQuotas, nil); err != nil {
+		return
+	}
+	existing := make(map[string]v3SpaceQuota)
+	for _, q := range destQuotas {
+		existing[q.Name] = q
+	}
+
+	for _, q := range srcQuotas {
+		dq, ok := existing[q.Name]
+		if !ok {
+			s.c.logger.UI.Say("Creating space quota %s...", terminal.EntityNameColor(q.Name))
+			request := map[string]interface{}{
+				"name":     q.Name,
+				"apps":     q.Apps,
+				"services": q.Services,
+				"routes":   q.Routes,
+				"relationships": map[string]interface{}{
+					"organization": v3ToOne{Data: &v3GUID{GUID: s.destOrg.GUID}},
+				},
+			}
+			if err = s.dest.Do("POST", "/v3/space_quotas", request, &dq); err != nil {
+				return fmt.Errorf("The space quota '%s' could not be created: %s", q.Name, err.Error())
+			}
+			s.changed = true
+		}
+
+		apply := v3ToMany{}
+		for _, space := range q.Relationships.Spaces.Data {
+			destGUID, copied := s.destSpaces[space.GUID]
+			if !copied {
+				continue
+			}
+			applied := false
+			for _, ds := range dq.Relationships.Spaces.Data {
+				applied = applied || ds.GUID == destGUID
+			}
+			if !applied {
+				apply.Data = append(apply.Data, v3GUID{GUID: destGUID})
+			}
+		}
+		if len(apply.Data) == 0 {
+			continue
+		}
+		s.c.logger.UI.Say("Applying space quota %s to %d spaces...", terminal.EntityNameColor(q.Name), len(apply.Data))
+		if err = s.dest.Do("POST", fmt.Sprintf("/v3/space_quotas/%s/relationships/spaces", dq.GUID), apply, nil); err != nil {
+			return fmt.Errorf("The space quota '%s' could not be applied: %s", q.Name, err.Error())
+		}
+		s.changed = true
+	}
+	return
+}
+
+// copyRoles - Gives the users of the source org and of the copied spaces
+// the same roles in the destination org and spaces. Users are identified
+// by their name and origin as their GUIDs differ between targets. Org
+// roles are copied first as users need one to be given a space role.
+func (s *orgSetup) copyRoles() (err error) {
+
+	if err = s.copyRolesOf("organization", s.srcOrg.GUID, s.destOrg.GUID, nil); err != nil {
+		return
+	}
+
+	srcGUIDs, destGUIDs := []string{}, []string{}
+	for srcGUID, destGUID := range s.destSpaces {
+		srcGUIDs = append(srcGUIDs, srcGUID)
+		destGUIDs = append(destGUIDs, destGUID)
+	}
+	return s.copyRolesOf("space", strings.Join(srcGUIDs, ","), strings.Join(destGUIDs, ","), s.destSpaces)
+}
+
+// copyRolesOf - Copies the roles of the given kind of the source orgs or
+// spaces to the destination orgs or spaces. The destination of a space
+// role is looked up in the given map while org roles are given to the
+// destination org.
+func (s *orgSetup) copyRolesOf(kind, srcGUIDs, destGUIDs string, destSpaces map[string]string) (err error) {
+
+	var (
+		srcRoles, destRoles []v3Role
+		srcUsers, destUsers map[string]v3User
+	)
+
+	// The destination roles are listed first so that the roles it
+	// does not know are also left out of the source roles
+	if destUsers, err = s.listRoles(s.dest, s.c.o.DestTarget, kind, destGUIDs, &destRoles); err != nil {
+		return
+	}
+	if srcUsers, err = s.listRoles(s.src, s.c.o.SourceTarget, kind, srcGUIDs, &srcRoles); err != nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	for _, r := range destRoles {
+		if u, ok := destUsers[r.userGUID()]; ok {
+			existing[roleKey(r, u, r.Relationships.Space.Data)] = true
+		}
+	}
+
+	for _, r := range srcRoles {
+		u, ok := srcUsers[r.userGUID()]
+		if !ok || u.Username == "" {
+			// Clients given a role have no user name
+			s.c.logger.DebugMessage("Skipping role '%s' of user '%s' without a name", r.Type, r.userGUID())
+			continue
+		}
+
+		var destSpace *v3GUID
+
+		target := map[string]interface{}{}
+		if kind == "space" {
+			destSpace = &v3GUID{GUID: destSpaces[r.Relationships.Space.Data.GUID]}
+			target["space"] = v3ToOne{Data: destSpace}
+		} else {
+			target["organization"] = v3ToOne{Data: &v3GUID{GUID: s.destOrg.GUID}}
+		}
+		if existing[roleKey(r, u, destSpace)] {
+			continue
+		}
+
+		target["user"] = map[string]interface{}{
+			"data": map[string]string{"username": u.Username, "origin": u.Origin},
+		}
+		request := map[string]interface{}{
+			"type":          r.Type,
+			"relationships": target,
+		}
+		s.c.logger.UI.Say("Giving user %s role %s...", terminal.EntityNameColor(u.Username), terminal.EntityNameColor(r.Type))
+		if err = s.dest.Do("POST", "/v3/roles", request, nil); err != nil {
+			// The user may not exist at the destination target
+			s.c.logger.UI.Warn("The role %s of user %s could not be copied: %s", r.Type, u.Username, err.Error())
+			err = nil
+			continue
+		}
+		s.changed = true
+	}
+	return
+}
+
+// roleKey - Identifies a role by its type, user and space
+func roleKey(r v3Role, u v3User, space *v3GUID) string {
+	key := r.Type + "/" + u.Origin + "/" + u.Username
+	if space != nil {
+		key += "/" + space.GUID
+	}
+	return key
+}
+
+// listRoles - Lists the roles of the given kind at the given target. A
+// target that does not know the space_supporter role rejects listing it
+// so the role is left out of the roles that are copied.
+func (s *orgSetup) listRoles(ccClient *helpers.CCClient, target, kind, guids string, roles *[]v3Role) (map[string]v3User, error) {
+
+	users, err := listV3Roles(ccClient, kind, guids, s.roleTypes(kind), roles)
+	if ccErr, ok := err.(*helpers.CCError); ok && kind == "space" && !s.noSpaceSupporter &&
+		(ccErr.StatusCode == http.StatusBadRequest || ccErr.StatusCode == http.StatusUnprocessableEntity) {
+
+		s.c.logger.UI.Warn("The target '%s' does not support the space_supporter role so it is not copied.", target)
+		s.noSpaceSupporter = true
+		return listV3Roles(ccClient, kind, guids, s.roleTypes(kind), roles)
+	}
+	return users, err
+}
+
+// roleTypes - The types of roles of the given kind that are copied
+func (s *orgSetup) roleTypes(kind string) []string {
+	if kind == "space" {
+		if s.noSpaceSupporter {
+			return []string{"space_manager", "space_developer", "space_auditor"}
+		}
+		return []string{"space_manager", "space_developer", "space_auditor", "space_supporter"}
+	}
+	return []string{"organization_user", "organization_manager", "organization_auditor", "organization_billing_manager"}
+}
+
+// listV3Roles - Lists the roles of the given types of the orgs or
+// spaces with the given GUIDs and returns their users by GUID
+func listV3Roles(ccClient *helpers.CCClient, kind, guids string, types []string, roles *[]v3Role) (map[string]v3User, error) {
+
+	var included struct {
+		Users []v3User `json:"users"`
+	}
+
+	users := make(map[string]v3User)
+	if guids == "" {
+		return users, nil
+	}
+	path := fmt.Sprintf("/v3/roles?%s_guids=%s&types=%s&include=user", kind, guids, strings.Join(types, ","))
+	if err := listV3(ccClient, path, roles, &included); err != nil {
+		return nil, err
+	}
+	for _, u := range included.Users {
+		users[u.GUID] = u
+	}
+	return users, nil
+}
+
+// findV3Resource - Returns the only resource listed by the
+// given path or nil if no resource is listed
+func findV3Resource(ccClient *helpers.CCClient, path string) (*v3Resource, error) {
+	resources, err := listV3Resources(ccClient, path)
+	if err != nil || len(resources) == 0 {
+		return nil, err
+	}
+	return &resources[0], nil
+}
+
+// listV3Resources - Returns the resources listed by the given path
+func listV3Resources(ccClient *helpers.CCClient, path string) (resources []v3Resource, err error) {
+	err = listV3(ccClient, path, &resources, nil)
+	return
+}
+
+// listV3 - Unmarshals the resources listed by the given v3 path into
+// the given slice and the included resources into the given struct.
+// The pages of the list are followed until the last one.
+func listV3(ccClient *helpers.CCClient, path string, resources interface{}, included interface{}) error {
+
+	var (
+		listed   []json.RawMessage
+		includes = make(map[string][]json.RawMessage)
+	)
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	next := fmt.Sprintf("%s%sper_page=%d", path, separator, v3ListSize)
+	for next != "" {
+		var page struct {
+			Pagination struct {
+				Next *struct {
+					Href string `json:"href"`
+				} `json:"next"`
+			} `json:"pagination"`
+			Resources []json.RawMessage            `json:"resources"`
+			Included  map[string][]json.RawMessage `json:"included"`
+		}
+		if err := ccClient.Do("GET", next, nil, &page); err != nil {
+			return err
+		}
+		listed = append(listed, page.Resources...)
+		for name, r := range page.Included {
+			includes[name] = append(includes[name], r...)
+		}
+
+		next = ""
+		if page.Pagination.Next != nil && page.Pagination.Next.Href != "" {
+			// The link to the next page is an absolute URL of the target
+			nextURL, err := url.Parse(page.Pagination.Next.Href)
+			if err != nil {
+				return err
+			}
+			next = nextURL.RequestURI()
+		}
+	}
+
+	if len(listed) > 0 {
+		if err := remarshal(listed, resources); err != nil {
+			return err
+		}
+	}
+	if included != nil && len(includes) > 0 {
+		return remarshal(includes, included)
+	}
+	return nil
+}
+
+// remarshal - Unmarshals the JSON encoding of the given value into v
+func remarshal(value interface{}, v interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,207 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"code.cloudfoundry.org/cli/plugin"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// copyPlan - The copies of a plan file with their options
+type copyPlan struct {
+	path     string
+	parallel int
+	force    bool
+	copies   []plannedCopy
+}
+
+type plannedCopy struct {
+	copyResult
+	o *CopyOptions
+}
+
+// copyResult - The result of one of the copies run together
+type copyResult struct {
+	name     string
+	exitCode int
+	duration time.Duration
+}
+
+// copyCmdFactory - Implemented by copy commands that can create
+// commands which run independently of each other so that the
+// copies of a plan can run at the same time
+type copyCmdFactory interface {
+	NewCopy() CopyCmd
+}
+
+// parsePlan - Reads the copies of the given plan file. The options of each
+// copy are taken from the plan and then from the options of the command.
+// Invalid copies are reported using the given function.
+func parsePlan(path string, a *copyArgs, fail func(format string, v ...interface{})) *copyPlan {
+
+	plan, err := helpers.ReadCopyPlan(path)
+	if err != nil {
+		fail("%s", err.Error())
+		return nil
+	}
+
+	p := &copyPlan{
+		path:     path,
+		parallel: plan.Parallel,
+		force:    a.Bool("force"),
+	}
+	for i, pc := range plan.Copies {
+
+		errs := []string{}
+		copyFail := func(format string, v ...interface{}) {
+			errs = append(errs, fmt.Sprintf(format, v...))
+		}
+
+		args, err := a.withOverrides(pc.Options, fmt.Sprintf("copy %d of the plan", i+1), "plan", "interactive")
+		if err != nil {
+			fail("%s", err.Error())
+			continue
+		}
+		o := &CopyOptions{
+			Retries:      defaultRetries,
+			SourceTarget: pc.Source.Target,
+			SourceOrg:    pc.Source.Org,
+			SourceSpace:  pc.Source.Space,
+			DestTarget:   pc.Destination.Target,
+			DestOrg:      pc.Destination.Org,
+			DestSpace:    pc.Destination.Space,
+		}
+		parseArgs("copy", args, o, copyFail)
+
+		if p.parallel > 1 && (o.SourceTarget == "" || o.DestTarget == "") {
+			copyFail("the source and destination targets must be given to run copies in parallel")
+		}
+		for _, e := range errs {
+			fail("Copy '%s': %s", pc.Name, e)
+		}
+		// The plan is confirmed as a whole
+		o.Force = true
+
+		p.copies = append(p.copies, plannedCopy{copyResult: copyResult{name: pc.Name}, o: o})
+	}
+	return p
+}
+
+// runPlan - Runs the copies of the plan. A copy that fails does not stop
+// the copies after it. Once all copies are done the result of each copy
+// is reported.
+func (c *CopyPlugin) runPlan(cli plugin.CliConnection, p *copyPlan) int {
+
+	confirmed, err := c.confirmPlan(p)
+	if err != nil {
+		c.ui.Failed(err.Error())
+		return ExitNotConfirmed
+	}
+	if !confirmed {
+		c.ui.Say("Copy cancelled.")
+		return ExitNotConfirmed
+	}
+
+	factory, canCreate := c.copyCmd.(copyCmdFactory)
+	if !canCreate {
+		p.parallel = 1
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan bool, p.parallel)
+	for i := range p.copies {
+		slots <- true
+		wg.Add(1)
+
+		go func(pc *plannedCopy) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			copyCmd := c.copyCmd
+			if canCreate {
+				copyCmd = factory.NewCopy()
+			}
+			pc.o.Parallel = p.parallel > 1
+
+			c.ui.Say("")
+			c.ui.Say("Running copy %s...", terminal.EntityNameColor(pc.name))
+			start := time.Now()
+			pc.exitCode = copyCmd.Execute(cli, pc.o)
+			pc.duration = time.Since(start)
+		}(&p.copies[i])
+	}
+	wg.Wait()
+
+	results := []copyResult{}
+	for _, pc := range p.copies {
+		results = append(results, pc.copyResult)
+	}
+	return reportResults(c.ui, results, "copies of the plan")
+}
+
+// reportResults - Lists the result of each of the given copies and
+// returns the exit code of all of them. The copies are described
+// as the given kind of copies when they fail.
+func reportResults(ui terminal.UI, results []copyResult, kind string) int {
+
+	failed := 0
+	ui.Say("")
+	table := ui.Table([]string{"copy", "result", "time"})
+	for _, r := range results {
+		result := terminal.SuccessColor("ok")
+		if r.exitCode != ExitOK {
+			result = terminal.FailureColor(fmt.Sprintf("failed: %s", exitCodeDescription(r.exitCode)))
+			failed++
+		}
+		table.Add(r.name, result, (r.duration - r.duration%time.Second).String())
+	}
+	table.Print()
+	ui.Say("")
+
+	switch {
+	case failed == 0:
+		ui.Ok()
+		return ExitOK
+	case failed == len(results):
+		ui.Failed("All %d %s failed.", failed, kind)
+		return ExitTotalFailure
+	default:
+		ui.Failed("%d of %d %s failed.", failed, len(results), kind)
+		return ExitPartialCopy
+	}
+}
+
+// confirmPlan - Lists the copies of the plan and asks for confirmation
+// unless --force is set
+func (c *CopyPlugin) confirmPlan(p *copyPlan) (bool, error) {
+
+	if p.force {
+		return true, nil
+	}
+	if !helpers.IsTerminal(os.Stdin) {
+		return false, errors.New("The copy cannot be confirmed as the input is not a terminal. Use --force to copy without confirmation.")
+	}
+
+	c.ui.Say("")
+	table := c.ui.Table([]string{"copy", "source", "destination"})
+	for _, pc := range p.copies {
+		source := helpers.PlanLocation{Target: pc.o.SourceTarget, Org: pc.o.SourceOrg, Space: pc.o.SourceSpace}.String()
+		if source == "" {
+			source = "current"
+		}
+		destination := helpers.PlanLocation{Target: pc.o.DestTarget, Org: pc.o.DestOrg, Space: pc.o.DestSpace}.String()
+		table.Add(pc.name, source, destination)
+	}
+	table.Print()
+	c.ui.Say("")
+
+	return c.ui.Confirm(fmt.Sprintf("Really run the %d copies of plan %s?",
+		len(p.copies), terminal.EntityNameColor(p.path))), nil
+}
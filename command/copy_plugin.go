@@ -1,20 +1,98 @@
 package command
 
 import (
+	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/cli/cf/flags"
+	"code.cloudfoundry.org/cli/cf/formatters"
 	"code.cloudfoundry.org/cli/cf/terminal"
 	"code.cloudfoundry.org/cli/cf/trace"
 
 	"code.cloudfoundry.org/cli/plugin"
+	"github.com/mevansam/cf-copy-plugin/helpers"
 )
 
+// defaultRetries - How many times failed Cloud Controller
+// calls and bit transfers are retried by default
+const defaultRetries = 3
+
+const (
+	stringOption = iota
+	boolOption
+	sliceOption
+)
+
+// servicesOnlyConflicts - Flags that only apply to copied
+// apps and cannot be used when only services are copied
+var servicesOnlyConflicts = []string{
+	"host-format", "domain", "droplet", "stream", "cache-dir", "cache-size",
+	"env", "env-file", "drop-env", "instances", "memory", "disk",
+	"no-start", "start-order", "wait", "timeout",
+	"blue-green", "keep-old", "strategy",
+}
+
+// copyFlag - A flag of the copy command
+type copyFlag struct {
+	name  string
+	short string
+	kind  int
+}
+
+// copyFlags - The flags of the copy command. Each flag can also be set
+// in a profile or with a CF_COPY_* environment variable.
+var copyFlags = []copyFlag{
+	{"source-space", "", stringOption},
+	{"source-org", "", stringOption},
+	{"source-target", "", stringOption},
+	{"apps", "a", stringOption},
+	{"interactive", "", boolOption},
+	{"host-format", "n", stringOption},
+	{"domain", "m", stringOption},
+	{"droplet", "c", boolOption},
+	{"stream", "", boolOption},
+	{"cache-dir", "", stringOption},
+	{"cache-size", "", stringOption},
+	{"env", "e", sliceOption},
+	{"env-file", "", stringOption},
+	{"drop-env", "", sliceOption},
+	{"instances", "", stringOption},
+	{"memory", "", stringOption},
+	{"disk", "", stringOption},
+	{"no-start", "", boolOption},
+	{"start-order", "", stringOption},
+	{"wait", "", boolOption},
+	{"timeout", "", stringOption},
+	{"ups", "s", stringOption},
+	{"service-types", "t", stringOption},
+	{"services-only", "o", boolOption},
+	{"recreate-services", "r", boolOption},
+	{"on-conflict", "", stringOption},
+	{"blue-green", "", boolOption},
+	{"keep-old", "", boolOption},
+	{"strategy", "", stringOption},
+	{"retries", "", stringOption},
+	{"delete-source", "", boolOption},
+	{"remap-routes", "", boolOption},
+	{"force", "f", boolOption},
+	{"allow-protected", "", boolOption},
+	{"to", "", sliceOption},
+	{"spaces", "", stringOption},
+	{"exclude-spaces", "", stringOption},
+	{"plan", "", stringOption},
+	{"config", "", stringOption},
+	{"profile", "", stringOption},
+	{"debug", "d", boolOption},
+}
+
 // CopyPlugin -
 type CopyPlugin struct {
-	ui      terminal.UI
-	copyCmd CopyCmd
+	ui       terminal.UI
+	copyCmd  CopyCmd
+	exitCode int
 }
 
 // NewCopyPlugin -
@@ -27,6 +105,14 @@ func NewCopyPlugin(copyCmd CopyCmd) *CopyPlugin {
 // Start -
 func (c *CopyPlugin) Start() {
 	plugin.Start(c)
+	if c.exitCode != 0 {
+		os.Exit(c.exitCode)
+	}
+}
+
+// ExitCode - Returns the exit code of the last command run
+func (c *CopyPlugin) ExitCode() int {
+	return c.exitCode
 }
 
 // GetMetadata -
@@ -43,117 +129,506 @@ func (c *CopyPlugin) GetMetadata() plugin.PluginMetadata {
 				Name:     "copy",
 				HelpText: "Copy current space artifacts to another space. Uses targets saved by 'Targets' plugin when copying to another Cloud Foundry target.",
 				UsageDetails: plugin.Usage{
-					Usage: "cf copy DEST_SPACE [DEST_ORG] [DEST_TARGET] " +
-						"[--apps|-a APPLICATIONS] [--host-format|-n HOST_FORMAT] [--domain|-d DOMAIN] [--droplet] " +
-						"[--ups|-s COPY_AS_UPS] [--services-only|-o] [--recreate-services|-r]" +
-						"[-debug|-d]",
+					Usage:   "cf copy DEST_SPACE [DEST_ORG] [DEST_TARGET] " + copyUsage,
+					Options: copyUsageOptions(),
+				},
+			},
+			{
+				Name:     "copy-promote",
+				HelpText: "Promote apps from a stage of a promotion chain to the next stage. The chain is read from the copy configuration file and the stage promoted from is the one the CLI is targeted at unless it is given.",
+				UsageDetails: plugin.Usage{
+					Usage:   "cf copy-promote CHAIN [STAGE] " + copyUsage,
+					Options: copyUsageOptions(),
+				},
+			},
+			{
+				Name:     "copy-org",
+				HelpText: "Copy all spaces of the current org to another org. Missing spaces are created and the org's private domains, space quotas and roles are copied before its spaces.",
+				UsageDetails: plugin.Usage{
+					Usage:   "cf copy-org DEST_ORG [DEST_TARGET] [--spaces SPACES] [--exclude-spaces SPACES] " + copyUsage,
+					Options: copyOrgUsageOptions(),
+				},
+			},
+			{
+				Name:     "copy-status",
+				HelpText: "Report the apps and services of the current space that were copied and are out of date with their source.",
+				UsageDetails: plugin.Usage{
+					Usage: "cf copy-status [-debug|-d]",
 					Options: map[string]string{
-						"-apps, -a":              "Copy only the given applications and their bound services. Default is to copy all applications.",
-						"-host-format, -n":       "Format of app route's hostname to make it unique i.e. \"{{.host}}-{{.space}}\".",
-						"-domain, -m":            "Domain to use to create routes for copied apps with same hostname.",
-						"-droplet, -c":           "Application droplet will be copied to the destination as is. Otherwise, the application bits will be re-pushed.",
-						"-ups, -s":               "Comma separated list of service instances that will be copied as user provided services in the target space.",
-						"-service-types, -t":     "Comma separated list of service types that will be copied as user provided services in the target space.",
-						"-services-only, -o":     "Make copies of services only. If a list of applications are provided then only services bound to that app will be copied.",
-						"-recreate-services, -r": "Recreates services at destination.",
-						"-debug, -d":             "Output debug messages.",
+						"-debug, -d": "Output debug messages.",
 					},
 				},
 			},
+			{
+				Name:     "move",
+				HelpText: "Move current space artifacts to another space. The source apps are stopped or deleted once their copies are healthy and their routes respond.",
+				UsageDetails: plugin.Usage{
+					Usage:   "cf move DEST_SPACE [DEST_ORG] [DEST_TARGET] [--delete-source] [--remap-routes] " + copyUsage,
+					Options: moveUsageOptions(),
+				},
+			},
 		},
 	}
 }
 
+// copyUsage - The usage of the options of the copy command
+const copyUsage = "[--source-space SOURCE_SPACE] [--source-org SOURCE_ORG] [--source-target SOURCE_TARGET] " +
+	"[--apps|-a APPLICATIONS|--interactive] [--host-format|-n HOST_FORMAT] [--domain|-d DOMAIN] [--droplet [--stream] [--cache-dir CACHE_DIR [--cache-size CACHE_SIZE]]] " +
+	"[--env|-e KEY=VALUE]... [--env-file ENV_FILE] [--drop-env PATTERN]... " +
+	"[--instances INSTANCES] [--memory MEMORY] [--disk DISK] " +
+	"[--no-start] [--start-order START_ORDER_FILE|auto] [--wait] [--timeout TIMEOUT] " +
+	"[--ups|-s COPY_AS_UPS] [--services-only|-o] [--recreate-services|-r] " +
+	"[--on-conflict skip|replace|rename|fail] [--blue-green [--keep-old]] [--strategy rolling] " +
+	"[--retries RETRIES] [--force|-f] [--allow-protected] [--to [[TARGET/]ORG/]SPACE]... [--plan PLAN_FILE] [--config CONFIG_FILE] [--profile PROFILE] [-debug|-d]"
+
+// copyUsageOptions - Returns the descriptions of the options of the copy command
+func copyUsageOptions() map[string]string {
+	return map[string]string{
+		"-source-space":          "Copy from the given space instead of the space the CLI is targeted at.",
+		"-source-org":            "Org of the source space. Default is the org the source target is targeted at.",
+		"-source-target":         "Copy from the given saved target instead of the current CLI target.",
+		"-apps, -a":              "Copy only the given applications and their bound services. Default is to copy all applications.",
+		"-interactive":           "Pick the applications to copy and the services to copy as user provided services from a list of the source applications and their services.",
+		"-host-format, -n":       "Format of app route's hostname to make it unique i.e. \"{{.host}}-{{.space}}\".",
+		"-domain, -m":            "Domain to use to create routes for copied apps with same hostname.",
+		"-droplet, -c":           "Application droplet will be copied to the destination as is. Otherwise, the application bits will be re-pushed. Droplets are copied by the Cloud Controller when the destination is on the same target.",
+		"-stream":                "Stream droplets copied to another target from the source to the destination without staging them on local disk.",
+		"-cache-dir":             "Cache the droplets of apps copied to another target with --droplet in the given directory so they are only downloaded from the source once. App bits are not cached and droplets copied within a target are copied by the Cloud Controller.",
+		"-cache-size":            "Maximum size of the droplet cache i.e. \"10G\". The least recently used droplets are evicted when it is exceeded. Default is 5G.",
+		"-env, -e":               "Set or override an environment variable of the copied applications. May be repeated.",
+		"-env-file":              "File of KEY=VALUE environment variable overrides. Variables following an '[APP_NAME]' section apply only to that application.",
+		"-drop-env":              "Remove environment variables whose names match the given pattern (i.e. \"SPRING_*\") from the copied applications. May be repeated.",
+		"-instances":             "Instance count of the copied applications given as a count, a percentage of the source i.e. \"50%\" or a comma separated list of 'APP:VALUE' overrides.",
+		"-memory":                "Memory limit of the copied applications given as a size i.e. \"512M\", a percentage of the source or a comma separated list of 'APP:VALUE' overrides.",
+		"-disk":                  "Disk limit of the copied applications given as a size i.e. \"1G\", a percentage of the source or a comma separated list of 'APP:VALUE' overrides.",
+		"-no-start":              "Leave the copied applications stopped.",
+		"-start-order":           "Start the copied applications in tiers read from a file with one line of app names per tier, or derived from network policies and user provided service routes if \"auto\". Each tier is started once the previous tier is healthy.",
+		"-wait":                  "Wait for all instances of the copied applications to be running and report their health. Exits with an error if any application is unhealthy.",
+		"-timeout":               "How long to wait for copied applications to start i.e. \"90s\" or \"10m\". Default is 5 minutes.",
+		"-ups, -s":               "Comma separated list of service instances that will be copied as user provided services in the target space.",
+		"-service-types, -t":     "Comma separated list of service types that will be copied as user provided services in the target space.",
+		"-services-only, -o":     "Make copies of services only. If a list of applications are provided then only services bound to that app will be copied.",
+		"-recreate-services, -r": "Recreates services at destination.",
+		"-on-conflict":           "How to handle apps and services that already exist at the destination. One of skip, replace, rename (the existing resource) or fail, or a list such as \"apps=rename,services=skip\".",
+		"-blue-green":            "Replace existing destination apps without downtime. The copy is pushed as 'APP_NAME-copy-TIMESTAMP' while the existing app keeps serving its routes. Once the copy is healthy the routes are moved to it, it is given the app's name and the existing app is deleted.",
+		"-keep-old":              "Keep the app replaced by a blue-green copy stopped as 'APP_NAME-venerable-TIMESTAMP' instead of deleting it.",
+		"-strategy":              "Use \"rolling\" to roll out the copies of apps that already exist at the destination using a v3 deployment of the copied droplet. The environment, scale and service bindings of the copy are carried over while the existing app keeps its routes. Apps stopped at the source cannot be rolled out.",
+		"-retries":               "How many times Cloud Controller calls and droplet transfers that fail with a transient error are retried. Default is 3.",
+		"-force, -f":             "Copy without asking for confirmation. Required when the input is not a terminal.",
+		"-allow-protected":       "Allow copying to a destination that is protected by the copy configuration file.",
+		"-to":                    "Copy to the given destination instead of the one given by the positional arguments. May be repeated to copy to several destinations in one run. The bits of the source apps are only downloaded once.",
+		"-plan":                  "Run the copies listed in the given plan file one after the other or in parallel. The source, destination and options of each copy are given by the plan. Options given on the command line apply to every copy.",
+		"-config":                "Copy configuration file to read profiles and protected destinations from. Default is \".cf-copy.yml\" in the current directory.",
+		"-profile":               "Name of the profile in the copy configuration file whose options are used. Options given on the command line or as CF_COPY_* environment variables take precedence.",
+		"-debug, -d":             "Output debug messages.",
+	}
+}
+
+// moveUsageOptions - Returns the descriptions of the options of the move command
+func moveUsageOptions() map[string]string {
+	options := copyUsageOptions()
+	options["-delete-source"] = "Delete the source apps once they have been moved instead of stopping them."
+	options["-remap-routes"] = "Move the routes of the source apps to the moved apps. Only possible when moving within the same target with the 'route_sharing' feature flag enabled."
+	return options
+}
+
+// copyOrgUsageOptions - Returns the descriptions of the options of the copy-org command
+func copyOrgUsageOptions() map[string]string {
+	options := copyUsageOptions()
+	options["-spaces"] = "Comma separated list of the names of the spaces to copy. Names may contain '*' wildcards. Default is to copy all spaces."
+	options["-exclude-spaces"] = "Comma separated list of the names of spaces not to copy. Names may contain '*' wildcards."
+	return options
+}
+
 // Run -
 func (c *CopyPlugin) Run(cliConnection plugin.CliConnection, args []string) {
 
 	c.ui = terminal.NewUI(os.Stdin, os.Stdout, terminal.NewTeePrinter(os.Stdout), trace.NewLogger(os.Stdout, false, "", ""))
 
 	switch args[0] {
-	case "copy":
-		if o, ok := c.parseCopyOptions(args[1:]); ok {
-			c.copyCmd.Execute(cliConnection, o)
+	case "copy", "move", "copy-promote", "copy-org":
+		o, plan, ok := c.parseCopyOptions(cliConnection, args[0], args[1:])
+		switch {
+		case !ok:
+			c.exitCode = ExitUsageError
+		case plan != nil:
+			c.exitCode = c.runPlan(cliConnection, plan)
+		default:
+			c.exitCode = c.copyCmd.Execute(cliConnection, o)
+		}
+	case "copy-status":
+		if o, ok := c.parseStatusOptions(args[1:]); ok {
+			c.exitCode = c.copyCmd.Execute(cliConnection, o)
+		} else {
+			c.exitCode = ExitUsageError
 		}
 	default:
 		return
 	}
 }
 
-func (c *CopyPlugin) parseCopyOptions(args []string) (*CopyOptions, bool) {
+func (c *CopyPlugin) parseCopyOptions(cli plugin.CliConnection, command string, args []string) (*CopyOptions, *copyPlan, bool) {
 
-	var i int
+	// Usage errors are collected so that they can all be reported at once
+	positionals, flagArgs, errs := splitArgs(args)
+	fail := func(format string, v ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, v...))
+	}
 
-	o := CopyOptions{}
+	o := CopyOptions{Retries: defaultRetries}
 
-	for i, arg := range args {
-		if strings.Index(arg, "-") == 0 {
-			break
+	if command != "copy-promote" && command != "copy-org" {
+		for i, arg := range positionals {
+			switch i {
+			case 0:
+				o.DestSpace = arg
+			case 1:
+				o.DestOrg = arg
+			case 2:
+				o.DestTarget = arg
+			default:
+				fail("Invalid positional argument '%s'.", arg)
+			}
 		}
-		switch i {
-		case 0:
-			o.DestSpace = arg
-		case 1:
-			o.DestOrg = arg
-		case 2:
-			o.DestTarget = arg
+	}
+
+	f := flags.New()
+	for _, cf := range copyFlags {
+		switch cf.kind {
+		case boolOption:
+			f.NewBoolFlag(cf.name, cf.short, "")
+		case sliceOption:
+			f.NewStringSliceFlag(cf.name, cf.short, "")
 		default:
-			c.ui.Failed("Invalid positional argument '%s'.", arg)
-			return nil, false
+			f.NewStringFlag(cf.name, cf.short, "")
 		}
 	}
 
-	if o.DestSpace == "" {
-		c.ui.Failed("At least a destination space must be provided.")
-		return nil, false
+	err := f.Parse(flagArgs...)
+	if err != nil {
+		c.ui.Failed(strings.Join(append(errs, err.Error()), "\n"))
+		return nil, nil, false
 	}
 
+	// Options not given on the command line are taken
+	// from the environment or the selected profile
+	a, err := newCopyArgs(f)
+	if err != nil {
+		c.ui.Failed(strings.Join(append(errs, err.Error()), "\n"))
+		return nil, nil, false
+	}
+	if a.IsSet("plan") {
+		if command != "copy" {
+			fail("The --plan option can only be used with 'cf copy'.")
+		}
+		if len(positionals) > 0 {
+			fail("Positional arguments cannot be used with --plan as the plan gives the destination of each copy.")
+		}
+		if a.IsSet("to") {
+			fail("The --to option cannot be used with --plan as the plan gives the destination of each copy.")
+		}
+		plan := parsePlan(a.String("plan"), a, fail)
+		if len(errs) > 0 {
+			c.ui.Failed(strings.Join(errs, "\n"))
+			return nil, nil, false
+		}
+		return nil, plan, true
+	}
+	if command == "copy-promote" {
+		// The source and destination are given by the
+		// stage promoted from and the stage after it
+		for _, name := range []string{"source-space", "source-org", "source-target", "to"} {
+			if a.IsSet(name) {
+				fail("The --%s option cannot be used when promoting.", name)
+			}
+		}
+		if a, err = parsePromotion(cli, &o, a, positionals); err != nil {
+			c.ui.Failed(strings.Join(append(errs, err.Error()), "\n"))
+			return nil, nil, false
+		}
+	} else if command == "copy-org" {
+		parseOrgCopy(&o, a, positionals, fail)
+	} else if a.IsSet("to") {
+		// The source is copied to several destinations
+		if len(positionals) > 0 {
+			fail("Positional arguments cannot be used with --to. All destinations must be given with --to.")
+		}
+		if command == "move" {
+			fail("Apps can only be moved to a single destination.")
+		}
+		o.Destinations = parseDestinations(a.StringSlice("to"), fail)
+	} else {
+		if o.DestSpace == "" {
+			o.DestSpace = a.positional("space")
+		}
+		if o.DestOrg == "" {
+			o.DestOrg = a.positional("org")
+		}
+		if o.DestTarget == "" {
+			o.DestTarget = a.positional("target")
+		}
+		if o.DestSpace == "" {
+			fail("At least a destination space must be provided.")
+		}
+	}
+	parseArgs(command, a, &o, fail)
+
+	if len(errs) > 0 {
+		c.ui.Failed(strings.Join(errs, "\n"))
+		return nil, nil, false
+	}
+	return &o, nil, true
+}
+
+// parseStatusOptions - Parses the options of the copy-status command
+func (c *CopyPlugin) parseStatusOptions(args []string) (*CopyOptions, bool) {
+
 	f := flags.New()
-	f.NewStringFlag("apps", "a", "")
-	f.NewStringFlag("host-format", "n", "")
-	f.NewStringFlag("domain", "m", "")
-	f.NewBoolFlag("droplet", "c", "")
-	f.NewStringFlag("ups", "s", "")
-	f.NewStringFlag("service-types", "t", "")
-	f.NewBoolFlag("services-only", "o", "")
-	f.NewBoolFlag("recreate-services", "r", "")
 	f.NewBoolFlag("debug", "d", "")
-
-	err := f.Parse(args[i:]...)
-	if err != nil {
+	if err := f.Parse(args...); err != nil {
 		c.ui.Failed(err.Error())
 		return nil, false
 	}
-	if f.IsSet("apps") {
-		o.SourceAppNames = strings.Split(f.String("apps"), ",")
+	if len(f.Args()) > 0 {
+		c.ui.Failed("Invalid positional argument '%s'.", f.Args()[0])
+		return nil, false
+	}
+
+	o := &CopyOptions{
+		Status:  true,
+		Retries: defaultRetries,
+		Debug:   f.Bool("debug"),
+	}
+	if trace := os.Getenv("CF_TRACE"); trace != "" {
+		o.Debug = true
+		o.TracePath = trace
+	}
+	return o, true
+}
+
+// parseArgs - Sets the copy options from the options given on the command
+// line, the environment or the configuration file. Invalid options are
+// reported using the given function.
+func parseArgs(command string, a *copyArgs, o *CopyOptions, fail func(format string, v ...interface{})) {
+
+	var err error
+
+	if a.IsSet("source-space") {
+		o.SourceSpace = a.String("source-space")
+	}
+	if a.IsSet("source-org") {
+		if !a.IsSet("source-space") && command != "copy-org" {
+			fail("A source space must be provided with the source org.")
+		}
+		o.SourceOrg = a.String("source-org")
+	}
+	if a.IsSet("source-target") {
+		o.SourceTarget = a.String("source-target")
+	}
+	if a.IsSet("apps") {
+		o.SourceAppNames = strings.Split(a.String("apps"), ",")
+	}
+	if a.IsSet("interactive") {
+		o.Interactive = a.Bool("interactive")
+		if o.Interactive && (a.IsSet("apps") || a.IsSet("ups")) {
+			fail("The --interactive option cannot be used with --apps or --ups.")
+		}
+	}
+	if a.IsSet("host-format") {
+		o.AppHostFormat = a.String("host-format")
+	}
+	if a.IsSet("domain") {
+		o.AppRouteDomain = a.String("domain")
+	}
+	if a.IsSet("droplet") {
+		o.CopyAsDroplet = a.Bool("droplet")
+	}
+	if a.IsSet("stream") {
+		if !o.CopyAsDroplet {
+			fail("The --stream option can only be used with --droplet.")
+		}
+		o.StreamDroplets = a.Bool("stream")
+	}
+	if a.IsSet("cache-dir") {
+		if !o.CopyAsDroplet {
+			fail("The --cache-dir option can only be used with --droplet.")
+		}
+		if o.StreamDroplets {
+			fail("The --cache-dir and --stream options cannot be used together.")
+		}
+		o.CacheDir = a.String("cache-dir")
+		o.CacheSize = defaultCacheSize
+	}
+	if a.IsSet("cache-size") {
+		if o.CacheDir == "" {
+			fail("The --cache-size option can only be used with --cache-dir.")
+		}
+		size, err := formatters.ToMegabytes(a.String("cache-size"))
+		if err != nil || size <= 0 {
+			fail("invalid cache size '%s'", a.String("cache-size"))
+		}
+		o.CacheSize = size * 1024 * 1024
+	}
+	if a.IsSet("env") || a.IsSet("env-file") || a.IsSet("drop-env") {
+		o.AppEnv = helpers.NewAppEnv()
+		if a.IsSet("env-file") {
+			if err = o.AppEnv.ParseFile(a.String("env-file")); err != nil {
+				fail("%s", err.Error())
+			}
+		}
+		for _, kv := range a.StringSlice("env") {
+			if err = o.AppEnv.ParseVar(kv); err != nil {
+				fail("%s", err.Error())
+			}
+		}
+		for _, p := range a.StringSlice("drop-env") {
+			if err = o.AppEnv.AddDropPattern(p); err != nil {
+				fail("%s", err.Error())
+			}
+		}
+	}
+	if a.IsSet("instances") || a.IsSet("memory") || a.IsSet("disk") {
+		o.AppScale = helpers.NewAppScale()
+		if a.IsSet("instances") {
+			if err = o.AppScale.ParseInstances(a.String("instances")); err != nil {
+				fail("%s", err.Error())
+			}
+		}
+		if a.IsSet("memory") {
+			if err = o.AppScale.ParseMemory(a.String("memory")); err != nil {
+				fail("%s", err.Error())
+			}
+		}
+		if a.IsSet("disk") {
+			if err = o.AppScale.ParseDisk(a.String("disk")); err != nil {
+				fail("%s", err.Error())
+			}
+		}
 	}
-	if f.IsSet("host-format") {
-		o.AppHostFormat = f.String("host-format")
+	if a.IsSet("no-start") {
+		o.NoStart = a.Bool("no-start")
 	}
-	if f.IsSet("domain") {
-		o.AppRouteDomain = f.String("domain")
+	if a.IsSet("start-order") {
+		if o.NoStart {
+			fail("The --no-start and --start-order options cannot be used together.")
+		}
+		if a.String("start-order") == "auto" {
+			o.StartOrderAuto = true
+		} else if o.StartTiers, err = helpers.ReadStartOrderFile(a.String("start-order")); err != nil {
+			fail("%s", err.Error())
+		}
 	}
-	if f.IsSet("droplet") {
-		o.CopyAsDroplet = f.Bool("droplet")
+	if a.IsSet("wait") {
+		o.Wait = a.Bool("wait")
+		if o.Wait && o.NoStart {
+			fail("The --wait and --no-start options cannot be used together.")
+		}
+	}
+	if a.IsSet("timeout") {
+		if o.Timeout, err = parseTimeout(a.String("timeout")); err != nil {
+			fail("%s", err.Error())
+		}
 	}
-	if f.IsSet("ups") {
-		o.ServiceInstancesToCopyAsUPS = strings.Split(f.String("ups"), ",")
+	if a.IsSet("ups") {
+		o.ServiceInstancesToCopyAsUPS = strings.Split(a.String("ups"), ",")
 	}
-	if f.IsSet("service-types") {
-		o.ServiceTypesToCopyAsUPS = strings.Split(f.String("service-types"), ",")
+	if a.IsSet("service-types") {
+		o.ServiceTypesToCopyAsUPS = strings.Split(a.String("service-types"), ",")
 	}
-	if f.IsSet("recreate-services") {
-		o.RecreateServices = f.Bool("recreate-services")
+	if a.IsSet("recreate-services") {
+		o.RecreateServices = a.Bool("recreate-services")
 	}
-	if f.IsSet("services-only") {
-		o.ServicesOnly = f.Bool("services-only")
+	if a.IsSet("on-conflict") {
+		if o.AppConflict, o.ServiceConflict, err = ParseConflictStrategies(a.String("on-conflict")); err != nil {
+			fail("%s", err.Error())
+		}
+		if o.RecreateServices {
+			if o.ServiceConflict != "" && o.ServiceConflict != ConflictReplace {
+				fail("The --recreate-services option can only be used with the replace strategy for services.")
+			}
+			o.ServiceConflict = ConflictReplace
+		}
+	}
+	if a.IsSet("blue-green") {
+		o.BlueGreen = a.Bool("blue-green")
+		if o.BlueGreen && (o.AppConflict != "" || o.NoStart) {
+			fail("The --blue-green option cannot be used with --no-start or an app conflict strategy.")
+		}
+	}
+	if a.IsSet("keep-old") {
+		if !o.BlueGreen {
+			fail("The --keep-old option can only be used with --blue-green.")
+		}
+		o.KeepOld = a.Bool("keep-old")
+	}
+	if a.IsSet("strategy") {
+		o.Strategy = a.String("strategy")
+		if o.Strategy != StrategyRolling {
+			fail("Invalid deployment strategy '%s'. The only supported strategy is 'rolling'.", o.Strategy)
+		}
+		if o.BlueGreen || o.AppConflict != "" || o.NoStart {
+			fail("The --strategy option cannot be used with --blue-green, --no-start or an app conflict strategy.")
+		}
 	}
-	if f.IsSet("debug") {
-		o.Debug = f.Bool("debug")
+	if a.IsSet("services-only") {
+		o.ServicesOnly = a.Bool("services-only")
+	}
+	if a.IsSet("retries") {
+		if o.Retries, err = strconv.Atoi(a.String("retries")); err != nil || o.Retries < 0 {
+			fail("invalid retries '%s'", a.String("retries"))
+		}
+	}
+	if command == "move" {
+		o.Move = true
+		if o.ServicesOnly || o.NoStart {
+			fail("Apps cannot be moved with --services-only or --no-start.")
+		}
+		// The source apps are only decommissioned once their copies are healthy
+		o.Wait = true
+		o.DeleteSource = a.Bool("delete-source")
+		o.RemapRoutes = a.Bool("remap-routes")
+	} else if a.IsSet("delete-source") || a.IsSet("remap-routes") {
+		fail("The --delete-source and --remap-routes options can only be used with 'cf move'.")
+	}
+	if command != "copy-org" && (a.IsSet("spaces") || a.IsSet("exclude-spaces")) {
+		fail("The --spaces and --exclude-spaces options can only be used with 'cf copy-org'.")
+	}
+	if a.IsSet("force") {
+		o.Force = a.Bool("force")
+	}
+	if a.IsSet("allow-protected") {
+		o.AllowProtected = a.Bool("allow-protected")
+	}
+	o.ProtectedDestinations = a.protected
+
+	if o.ServicesOnly {
+		appOptions := []string{}
+		for _, name := range servicesOnlyConflicts {
+			if a.IsSet(name) {
+				appOptions = append(appOptions, "--"+name)
+			}
+		}
+		if len(appOptions) > 0 {
+			fail("The --services-only option cannot be used with %s as no apps are copied.", strings.Join(appOptions, ", "))
+		}
+	}
+	if a.IsSet("debug") {
+		o.Debug = a.Bool("debug")
 	}
 	trace := os.Getenv("CF_TRACE")
 	if trace != "" {
 		o.Debug = true
 		o.TracePath = trace
 	}
-	return &o, true
+}
+
+// parseTimeout - Parses a duration such as "10m" or a number of seconds
+func parseTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if timeout, err := time.ParseDuration(value); err == nil && timeout > 0 {
+		return timeout, nil
+	}
+	return 0, fmt.Errorf("invalid timeout '%s'", value)
 }
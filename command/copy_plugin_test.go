@@ -1,6 +1,14 @@
 package command_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	plugin_models "code.cloudfoundry.org/cli/plugin/models"
 	. "code.cloudfoundry.org/cli/plugin/pluginfakes"
 	io_helpers "code.cloudfoundry.org/cli/util/testhelpers/io"
 	. "github.com/mevansam/cf-copy-plugin/command"
@@ -115,7 +123,37 @@ var _ = Describe("Copy Plugin Tests", func() {
 			Expect(output[0]).To(Equal("Done"))
 		})
 
-		It("Should recognize missing space", func() {
+		It("Should parse environment overrides", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.AppEnv).NotTo(BeNil())
+				env := o.AppEnv.Apply("fake_app", map[string]interface{}{
+					"SPRING_PROFILES_ACTIVE": "dev",
+					"SPRING_DATASOURCE":      "dev_db",
+					"LOG_LEVEL":              "debug",
+					"KEEP":                   "this",
+				})
+				Expect(env).To(Equal(map[string]interface{}{
+					"SPRING_PROFILES_ACTIVE": "staging",
+					"LOG_LEVEL":              "info",
+					"KEEP":                   "this",
+				}))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--env", "SPRING_PROFILES_ACTIVE=staging",
+					"--env", "LOG_LEVEL=info",
+					"--drop-env", "SPRING_*",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+		})
+
+		It("Should not accept an invalid environment override", func() {
 
 			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
 				Fail("CLI argument parsing should have failed and been handled.")
@@ -124,17 +162,46 @@ var _ = Describe("Copy Plugin Tests", func() {
 			output := io_helpers.CaptureOutput(func() {
 				copyPluginFake.Run(fakeCliConnection, []string{
 					"copy",
-					"--apps", "fake_app",
-					"--ups", "fake_svc1,fake_svc2",
-					"--services-only",
+					"fake_space",
+					"--env", "LOG_LEVEL",
 				})
 			})
 
 			Expect(output[0]).To(Equal("FAILED"))
-			Expect(output[1]).To(Equal("At least a destination space must be provided."))
+			Expect(output[1]).To(Equal("invalid environment variable 'LOG_LEVEL', expected KEY=VALUE"))
 		})
 
-		It("Should not accept extra positional arg", func() {
+		It("Should parse scaling overrides", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.AppScale).NotTo(BeNil())
+
+				app := models.ApplicationFields{Name: "fake_app1", InstanceCount: 4, Memory: 1024, DiskQuota: 2048}
+				instances, memory, disk := o.AppScale.Apply(app)
+				Expect(instances).To(Equal(1))
+				Expect(memory).To(Equal(int64(512)))
+				Expect(disk).To(Equal(int64(2048)))
+
+				app.Name = "fake_app2"
+				instances, memory, disk = o.AppScale.Apply(app)
+				Expect(instances).To(Equal(2))
+				Expect(memory).To(Equal(int64(256)))
+				Expect(disk).To(Equal(int64(2048)))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--instances", "1,fake_app2:50%",
+					"--memory", "50%,fake_app2:256M",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+		})
+
+		It("Should not accept an invalid scaling override", func() {
 
 			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
 				Fail("CLI argument parsing should have failed and been handled.")
@@ -144,14 +211,918 @@ var _ = Describe("Copy Plugin Tests", func() {
 				copyPluginFake.Run(fakeCliConnection, []string{
 					"copy",
 					"fake_space",
-					"fake_org",
-					"fake_target",
-					"invalid_argument",
+					"--memory", "fake_app:lots",
 				})
 			})
 
 			Expect(output[0]).To(Equal("FAILED"))
-			Expect(output[1]).To(Equal("Invalid positional argument 'invalid_argument'."))
+			Expect(output[1]).To(Equal("invalid memory value 'lots'"))
 		})
-	})
-})
+
+		It("Should parse an automatic start order", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.NoStart).To(BeFalse())
+				Expect(o.StartOrderAuto).To(BeTrue())
+				Expect(o.StartTiers).To(BeEmpty())
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--start-order", "auto",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+		})
+
+		It("Should not accept no-start with a start order", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--no-start",
+					"--start-order", "auto",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The --no-start and --start-order options cannot be used together."))
+		})
+
+		It("Should parse wait options", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.Wait).To(BeTrue())
+				Expect(o.Timeout).To(Equal(90 * time.Second))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--wait",
+					"--timeout", "90",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not accept wait with no-start", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--no-start",
+					"--wait",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The --wait and --no-start options cannot be used together."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should parse options from a profile and the environment", func() {
+
+			dir, err := ioutil.TempDir("", "copy-profile")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			config := filepath.Join(dir, "copy.yml")
+			Expect(ioutil.WriteFile(config, []byte(cf_copy_profiles), 0644)).To(Succeed())
+
+			os.Setenv("CF_COPY_HOST_FORMAT", "{{.host}}-env")
+			defer os.Unsetenv("CF_COPY_HOST_FORMAT")
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.DestSpace).To(Equal("fake_profile_space"))
+				Expect(o.DestOrg).To(Equal("fake_profile_org"))
+				Expect(o.SourceAppNames).To(Equal([]string{"fake_app1"}))
+				Expect(o.AppHostFormat).To(Equal("{{.host}}-env"))
+				Expect(o.CopyAsDroplet).To(BeTrue())
+				Expect(o.ServiceInstancesToCopyAsUPS).To(Equal([]string{"fake_svc1", "fake_svc2"}))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"--config", config,
+					"--profile", "dev-to-qa",
+					"--apps", "fake_app1",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not accept unknown options in a profile", func() {
+
+			dir, err := ioutil.TempDir("", "copy-profile")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			config := filepath.Join(dir, "copy.yml")
+			Expect(ioutil.WriteFile(config, []byte(cf_copy_profiles), 0644)).To(Succeed())
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"--config", config,
+					"--profile", "invalid",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("unknown option 'host' in profile 'invalid'"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should parse confirmation options and protected destinations", func() {
+
+			dir, err := ioutil.TempDir("", "copy-profile")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			config := filepath.Join(dir, "copy.yml")
+			Expect(ioutil.WriteFile(config, []byte(cf_copy_profiles), 0644)).To(Succeed())
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.Force).To(BeTrue())
+				Expect(o.AllowProtected).To(BeTrue())
+				Expect(o.ProtectedDestinations).To(HaveLen(2))
+				Expect(o.ProtectedDestinations[0].Matches("fake_prod_target", "fake_org", "fake_space")).To(BeTrue())
+				Expect(o.ProtectedDestinations[1].Matches("fake_target", "fake_profile_org", "production")).To(BeTrue())
+				Expect(o.ProtectedDestinations[1].Matches("fake_target", "fake_profile_org", "qa")).To(BeFalse())
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"production",
+					"--config", config,
+					"-f",
+					"--allow-protected",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not take confirmation options from the environment", func() {
+
+			os.Setenv("CF_COPY_FORCE", "true")
+			defer os.Unsetenv("CF_COPY_FORCE")
+			os.Setenv("CF_COPY_ALLOW_PROTECTED", "true")
+			defer os.Unsetenv("CF_COPY_ALLOW_PROTECTED")
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.Force).To(BeFalse())
+				Expect(o.AllowProtected).To(BeFalse())
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not accept apps or services with interactive selection", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--interactive",
+					"--ups", "fake_svc",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The --interactive option cannot be used with --apps or --ups."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should parse move options", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.DestSpace).To(Equal("fake_space"))
+				Expect(o.Move).To(BeTrue())
+				Expect(o.Wait).To(BeTrue())
+				Expect(o.DeleteSource).To(BeTrue())
+				Expect(o.RemapRoutes).To(BeFalse())
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"move",
+					"fake_space",
+					"--delete-source",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not accept move options when copying", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--remap-routes",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The --delete-source and --remap-routes options can only be used with 'cf move'."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should parse several destinations", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.DestSpace).To(BeEmpty())
+				Expect(o.Destinations).To(Equal([]Destination{
+					{Space: "fake_space1"},
+					{Org: "fake_org", Space: "fake_space2"},
+					{Target: "fake_target", Org: "fake_org", Space: "fake_space3"},
+				}))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"--to", "fake_space1",
+					"--to", "fake_org/fake_space2",
+					"--to", "fake_target/fake_org/fake_space3",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not accept invalid destinations", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--to", "fake_org/",
+					"--to", "fake_space1",
+					"--to", "fake_space1",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("Positional arguments cannot be used with --to. All destinations must be given with --to."))
+			Expect(output[2]).To(Equal("invalid destination 'fake_org/'. A destination is given as [[TARGET/]ORG/]SPACE."))
+			Expect(output[3]).To(Equal("The destination 'fake_space1' is given more than once."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should promote from the current stage to the next stage", func() {
+
+			dir, err := ioutil.TempDir("", "copy-profile")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			config := filepath.Join(dir, "copy.yml")
+			Expect(ioutil.WriteFile(config, []byte(cf_copy_profiles), 0644)).To(Succeed())
+
+			fakeCliConnection.GetCurrentOrgReturns(plugin_models.Organization{
+				OrganizationFields: plugin_models.OrganizationFields{Name: "fake_org"}}, nil)
+			fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{
+				SpaceFields: plugin_models.SpaceFields{Name: "fake_dev_space"}}, nil)
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.SourceOrg).To(Equal("fake_org"))
+				Expect(o.SourceSpace).To(Equal("fake_dev_space"))
+				Expect(o.DestTarget).To(Equal("fake_qa_target"))
+				Expect(o.DestOrg).To(Equal("fake_org"))
+				Expect(o.DestSpace).To(Equal("fake_qa_space"))
+				Expect(o.AppHostFormat).To(Equal("{{.host}}-cli"))
+				Expect(o.ServiceInstancesToCopyAsUPS).To(Equal([]string{"fake_svc"}))
+				Expect(o.AppScale).NotTo(BeNil())
+				Expect(*o.Promotion).To(Equal(Promotion{
+					Chain:       "release",
+					From:        "dev",
+					To:          "qa",
+					HistoryPath: filepath.Join(dir, ".cf-copy-history"),
+				}))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy-promote",
+					"release",
+					"--config", config,
+					"--host-format", "{{.host}}-cli",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not promote from the last stage of a chain", func() {
+
+			dir, err := ioutil.TempDir("", "copy-profile")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			config := filepath.Join(dir, "copy.yml")
+			Expect(ioutil.WriteFile(config, []byte(cf_copy_profiles), 0644)).To(Succeed())
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy-promote",
+					"release", "prod",
+					"--config", config,
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The stage 'prod' is the last stage of chain 'release'."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should run the copies of a plan", func() {
+
+			dir, err := ioutil.TempDir("", "copy-plan")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			plan := filepath.Join(dir, "plan.yml")
+			Expect(ioutil.WriteFile(plan, []byte(cf_copy_plan), 0644)).To(Succeed())
+
+			copies := []*CopyOptions{}
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				copies = append(copies, o)
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"--plan", plan,
+					"--droplet",
+					"--force",
+				})
+			})
+
+			Expect(copies).To(HaveLen(2))
+			Expect(copies[0].SourceSpace).To(Equal("fake_src_space"))
+			Expect(copies[0].DestTarget).To(Equal("fake_target"))
+			Expect(copies[0].DestSpace).To(Equal("fake_space1"))
+			Expect(copies[0].SourceAppNames).To(Equal([]string{"fake_app1", "fake_app2"}))
+			Expect(copies[0].CopyAsDroplet).To(BeTrue())
+			Expect(copies[0].Force).To(BeTrue())
+			Expect(copies[1].DestSpace).To(Equal("fake_space2"))
+			Expect(copies[1].SourceAppNames).To(BeEmpty())
+			Expect(copies[1].CopyAsDroplet).To(BeTrue())
+
+			Expect(output).To(ContainElement(ContainSubstring("fake_target/fake_org/fake_space1")))
+			Expect(output).To(ContainElement(ContainSubstring("team2")))
+			Expect(output).To(ContainElement("OK"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should run the copies of a plan in parallel and continue after a copy fails", func() {
+
+			dir, err := ioutil.TempDir("", "copy-plan")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			plan := filepath.Join(dir, "plan.yml")
+			Expect(ioutil.WriteFile(plan, []byte(cf_copy_parallel_plan), 0644)).To(Succeed())
+
+			var (
+				mutex      sync.Mutex
+				running    int
+				maxRunning int
+				copied     []string
+				parallel   []bool
+			)
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommandFactory(func(o *CopyOptions) int {
+				mutex.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				copied = append(copied, o.DestSpace)
+				parallel = append(parallel, o.Parallel)
+				mutex.Unlock()
+
+				time.Sleep(100 * time.Millisecond)
+
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+
+				if o.DestSpace == "fake_space1" {
+					return ExitDestinationNotFound
+				}
+				return ExitOK
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"--plan", plan,
+					"--force",
+				})
+			})
+
+			Expect(copied).To(ConsistOf("fake_space1", "fake_space2", "fake_space3"))
+			Expect(parallel).To(Equal([]bool{true, true, true}))
+			Expect(maxRunning).To(Equal(2))
+			Expect(output).To(ContainElement(ContainSubstring("team1")))
+			Expect(output).To(ContainElement("1 of 3 copies of the plan failed."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitPartialCopy))
+		})
+
+		It("Should report all invalid copies of a plan", func() {
+
+			dir, err := ioutil.TempDir("", "copy-plan")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+
+			plan := filepath.Join(dir, "plan.yml")
+			Expect(ioutil.WriteFile(plan, []byte(cf_copy_plan), 0644)).To(Succeed())
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--plan", plan,
+					"--keep-old",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("Positional arguments cannot be used with --plan as the plan gives the destination of each copy."))
+			Expect(output[2]).To(Equal("Copy 'fake_target/fake_org/fake_space1': The --keep-old option can only be used with --blue-green."))
+			Expect(output[3]).To(Equal("Copy 'team2': The --keep-old option can only be used with --blue-green."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should parse org copy options", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.DestOrg).To(Equal("fake_org"))
+				Expect(o.DestTarget).To(Equal("fake_target"))
+				Expect(o.DestSpace).To(BeEmpty())
+				Expect(o.SourceOrg).To(Equal("fake_src_org"))
+				Expect(o.OrgCopy).NotTo(BeNil())
+				Expect(o.OrgCopy.IncludeSpaces).To(Equal([]string{"dev*", "test"}))
+				Expect(o.OrgCopy.ExcludeSpaces).To(Equal([]string{"dev-scratch"}))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy-org",
+					"fake_org",
+					"fake_target",
+					"--source-org", "fake_src_org",
+					"--spaces", "dev*,test",
+					"--exclude-spaces", "dev-scratch",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should not accept space options when copying an org", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy-org",
+					"--source-space", "fake_src_space",
+					"--spaces", "dev[",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The destination org must be provided."))
+			Expect(output[2]).To(Equal("The --source-space option cannot be used when copying an org."))
+			Expect(output[3]).To(Equal("invalid space pattern 'dev['"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should parse copy status options", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.Status).To(BeTrue())
+				Expect(o.Debug).To(BeTrue())
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{"copy-status", "-d"})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should parse source options", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.DestSpace).To(Equal("fake_space"))
+				Expect(o.SourceSpace).To(Equal("fake_src_space"))
+				Expect(o.SourceOrg).To(Equal("fake_src_org"))
+				Expect(o.SourceTarget).To(Equal("fake_src_target"))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--source-space", "fake_src_space",
+					"--source-org", "fake_src_org",
+					"--source-target", "fake_src_target",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+		})
+
+		It("Should not accept a source org without a source space", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--source-org", "fake_src_org",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("A source space must be provided with the source org."))
+		})
+
+		It("Should parse conflict strategies", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.AppConflict).To(Equal(ConflictRename))
+				Expect(o.ServiceConflict).To(Equal(ConflictSkip))
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--on-conflict", "apps=rename,services=skip",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+		})
+
+		It("Should not accept an invalid conflict strategy", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--on-conflict", "apps=overwrite",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("invalid conflict strategy 'apps=overwrite', expected one of skip, replace, rename or fail"))
+		})
+
+		It("Should not accept stream without droplet", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--stream",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The --stream option can only be used with --droplet."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should not accept an invalid cache size", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--droplet",
+					"--cache-dir", "/tmp/droplets",
+					"--cache-size", "lots",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("invalid cache size 'lots'"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should not accept a cache directory with streamed droplets", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--droplet",
+					"--stream",
+					"--cache-dir", "/tmp/droplets",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The --cache-dir and --stream options cannot be used together."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should not accept an invalid number of retries", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--retries", "many",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("invalid retries 'many'"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should not accept keep-old without blue-green", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--keep-old",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("The --keep-old option can only be used with --blue-green."))
+		})
+
+		It("Should not accept an unknown deployment strategy", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--strategy", "canary",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("Invalid deployment strategy 'canary'. The only supported strategy is 'rolling'."))
+		})
+
+		It("Should accept positional arguments after flags", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Expect(o.DestSpace).To(Equal("fake_space"))
+				Expect(o.DestOrg).To(Equal("fake_org"))
+				Expect(o.SourceAppNames).To(Equal([]string{"fake_app"}))
+				Expect(o.CopyAsDroplet).To(BeTrue())
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"--apps", "fake_app",
+					"fake_space",
+					"--droplet",
+					"fake_org",
+				})
+			})
+
+			Expect(output[0]).To(Equal("Done"))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitOK))
+		})
+
+		It("Should report all usage errors at once", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"--servces-only",
+					"--keep-old",
+					"--services-only",
+					"--droplet",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("Unknown option '--servces-only'. Did you mean '--services-only'?"))
+			Expect(output[2]).To(Equal("The --keep-old option can only be used with --blue-green."))
+			Expect(output[3]).To(Equal("The --services-only option cannot be used with --droplet, --keep-old as no apps are copied."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should recognize missing space", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"--apps", "fake_app",
+					"--ups", "fake_svc1,fake_svc2",
+					"--services-only",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("At least a destination space must be provided."))
+			Expect(copyPluginFake.ExitCode()).To(Equal(ExitUsageError))
+		})
+
+		It("Should not accept extra positional arg", func() {
+
+			copyPluginFake := NewCopyPlugin(NewMockCopyCommand(func(o *CopyOptions) {
+				Fail("CLI argument parsing should have failed and been handled.")
+			}))
+
+			output := io_helpers.CaptureOutput(func() {
+				copyPluginFake.Run(fakeCliConnection, []string{
+					"copy",
+					"fake_space",
+					"fake_org",
+					"fake_target",
+					"invalid_argument",
+				})
+			})
+
+			Expect(output[0]).To(Equal("FAILED"))
+			Expect(output[1]).To(Equal("Invalid positional argument 'invalid_argument'."))
+		})
+	})
+})
+
+const cf_copy_profiles = `profiles:
+  dev-to-qa:
+    space: fake_profile_space
+    org: fake_profile_org
+    apps: [fake_app1, fake_app2]
+    host-format: "{{.host}}-profile"
+    droplet: true
+    ups: [fake_svc1, fake_svc2]
+  invalid:
+    space: fake_profile_space
+    host: fake_host
+chains:
+  release:
+    - name: dev
+      org: fake_org
+      space: fake_dev_space
+    - name: qa
+      target: fake_qa_target
+      org: fake_org
+      space: fake_qa_space
+      options:
+        host-format: "{{.host}}-qa"
+        instances: 2
+        ups: [fake_svc]
+    - name: prod
+      target: fake_prod_target
+      org: fake_prod_org
+      space: fake_prod_space
+protected:
+  - target: fake_prod_target
+  - org: fake_profile_org
+    space: prod*
+`
+
+const cf_copy_plan = `copies:
+  - source:
+      space: fake_src_space
+    destination:
+      target: fake_target
+      org: fake_org
+      space: fake_space1
+    options:
+      apps: [fake_app1, fake_app2]
+  - name: team2
+    destination:
+      space: fake_space2
+`
+
+const cf_copy_parallel_plan = `parallel: 2
+copies:
+  - name: team1
+    source:
+      target: fake_src_target
+    destination:
+      target: fake_target
+      space: fake_space1
+  - name: team2
+    source:
+      target: fake_src_target
+    destination:
+      target: fake_target
+      space: fake_space2
+  - name: team3
+    source:
+      target: fake_src_target
+    destination:
+      target: fake_target
+      space: fake_space3
+`
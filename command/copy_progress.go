@@ -0,0 +1,108 @@
+package command
+
+import (
+	"code.cloudfoundry.org/cli/cf/terminal"
+)
+
+// Actions of the transfers reported by the progress sessions and the
+// steps of copying an application they correspond to
+var transferSteps = map[string]string{
+	"downloading app bits": "download bits",
+	"downloading droplet":  "download droplet",
+	"uploading droplet":    "upload droplet",
+}
+
+// appSteps - Tracks the steps of copying an application and reports
+// each step as it is started along with how many steps remain
+type appSteps struct {
+	ui    terminal.UI
+	name  string
+	steps []string
+	next  int
+}
+
+// newAppSteps - Creates a tracker for the given steps of
+// copying the application with the given name
+func (c *CopyCommand) newAppSteps(name string, steps ...string) *appSteps {
+	return &appSteps{ui: c.logger.UI, name: name, steps: steps}
+}
+
+// begin - Reports the start of the given step. Steps
+// that were skipped are counted as done.
+func (s *appSteps) begin(step string) {
+	for i := s.next; i < len(s.steps); i++ {
+		if s.steps[i] == step {
+			s.next = i + 1
+			s.ui.Say("[%d/%d] %s: %s...", s.next, len(s.steps),
+				terminal.EntityNameColor(s.name), step)
+			return
+		}
+	}
+}
+
+// trackAppSteps - Creates the trackers of the steps of copying each
+// application. The applications manager downloads the source apps
+// before it creates them and uploads their droplets whereas apps that
+// are copied directly are created before their droplet is transferred.
+// Binding services is not reported as a step by either as the
+// applications manager binds them without reporting it.
+func (c *CopyCommand) trackAppSteps() {
+
+	var steps []string
+
+	switch {
+	case c.copiesDropletsDirectly():
+		transferStep := "transfer droplet"
+		if c.o.SourceTarget == c.o.DestTarget {
+			transferStep = "copy droplet"
+		}
+		steps = []string{"create", transferStep, "map routes", "start"}
+	case c.o.CopyAsDroplet:
+		steps = []string{"download droplet", "create", "upload droplet", "start"}
+	default:
+		steps = []string{"download bits", "create", "start"}
+	}
+	if c.o.NoStart {
+		steps = steps[:len(steps)-1]
+	}
+
+	c.appSteps = make(map[string]*appSteps)
+	for _, name := range c.o.SourceAppNames {
+		c.appSteps[name] = c.newAppSteps(name, steps...)
+	}
+}
+
+// beginAppStep - Reports the start of a step of copying the
+// application with the given name if its steps are tracked
+func (c *CopyCommand) beginAppStep(name, step string) {
+	if s, ok := c.appSteps[name]; ok {
+		s.begin(step)
+	}
+}
+
+// srcTransferStarted - Reports the download of a source application
+func (c *CopyCommand) srcTransferStarted(appGUID, action string) {
+	for _, a := range c.srcApps {
+		if a.GUID == appGUID {
+			c.beginAppStep(a.Name, transferSteps[action])
+			return
+		}
+	}
+}
+
+// destTransferStarted - Reports the upload to a copied application
+func (c *CopyCommand) destTransferStarted(appGUID, action string) {
+	app, err := c.destCCSession.Applications().GetApp(appGUID)
+	if err != nil {
+		c.logger.DebugMessage("Unable to read app '%s': %s", appGUID, err.Error())
+		return
+	}
+	name := app.Name
+	for _, bg := range c.blueGreenApps {
+		if bg.newName == name {
+			name = bg.name
+			break
+		}
+	}
+	c.beginAppStep(name, transferSteps[action])
+}
@@ -0,0 +1,151 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"code.cloudfoundry.org/cli/plugin"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// Promotion - A copy from a stage of a promotion chain to the next stage
+type Promotion struct {
+	Chain string
+	From  string
+	To    string
+
+	// HistoryPath - The file the promotion is recorded in
+	HistoryPath string
+}
+
+// parsePromotion - Sets the source and destination of a promotion from
+// the chain and the optional stage given as positional arguments. If no
+// stage is given the stage promoted from is the one whose org and space
+// the CLI is targeted at. The returned args use the options of the stage
+// promoted to for options not given on the command line or the environment.
+func parsePromotion(cli plugin.CliConnection, o *CopyOptions, a *copyArgs, positionals []string) (args *copyArgs, err error) {
+
+	var stages []helpers.PromotionStage
+
+	switch {
+	case len(positionals) == 0:
+		return nil, errors.New("The promotion chain must be provided.")
+	case len(positionals) > 2:
+		return nil, fmt.Errorf("Invalid positional argument '%s'.", positionals[2])
+	case a.config == "":
+		return nil, fmt.Errorf("The chain '%s' cannot be used as no %s file was found.", positionals[0], helpers.CopyConfigFile)
+	}
+
+	chain := positionals[0]
+	if stages, err = helpers.ReadPromotionChain(a.config, chain); err != nil {
+		return
+	}
+
+	from := -1
+	if len(positionals) == 2 {
+		for i, s := range stages {
+			if s.Name == positionals[1] {
+				from = i
+				break
+			}
+		}
+		if from == -1 {
+			return nil, fmt.Errorf("The stage '%s' is not part of chain '%s'.", positionals[1], chain)
+		}
+	} else if from, err = currentStage(cli, chain, stages); err != nil {
+		return
+	}
+	if from == len(stages)-1 {
+		return nil, fmt.Errorf("The stage '%s' is the last stage of chain '%s'.", stages[from].Name, chain)
+	}
+
+	src, dest := stages[from], stages[from+1]
+	if args, err = a.withOverrides(dest.Options, fmt.Sprintf("stage '%s'", dest.Name)); err != nil {
+		return
+	}
+
+	o.SourceTarget = src.Target
+	o.SourceOrg = src.Org
+	o.SourceSpace = src.Space
+	o.DestTarget = dest.Target
+	o.DestOrg = dest.Org
+	o.DestSpace = dest.Space
+
+	o.Promotion = &Promotion{
+		Chain:       chain,
+		From:        src.Name,
+		To:          dest.Name,
+		HistoryPath: filepath.Join(filepath.Dir(a.config), helpers.PromotionHistoryFile),
+	}
+	return
+}
+
+// currentStage - Returns the index of the stage of the chain whose org
+// and space the CLI is targeted at. The targets of the stages are not
+// compared so stages must differ in their org or space to be found.
+func currentStage(cli plugin.CliConnection, chain string, stages []helpers.PromotionStage) (int, error) {
+
+	org, err := cli.GetCurrentOrg()
+	if err != nil {
+		return -1, err
+	}
+	space, err := cli.GetCurrentSpace()
+	if err != nil {
+		return -1, err
+	}
+
+	current := -1
+	for i, s := range stages {
+		if s.Space == space.Name && (s.Org == "" || s.Org == org.Name) {
+			if current != -1 {
+				return -1, fmt.Errorf("More than one stage of chain '%s' matches the current org and space. The stage to promote from must be given.", chain)
+			}
+			current = i
+		}
+	}
+	if current == -1 {
+		return -1, fmt.Errorf("The current org and space are not a stage of chain '%s'. The stage to promote from must be given.", chain)
+	}
+	return current, nil
+}
+
+// recordPromotion - Records the droplets of the promoted source
+// applications in the promotion history
+func (c *CopyCommand) recordPromotion() (err error) {
+
+	var ccClient *helpers.CCClient
+
+	if ccClient, err = c.newCCClient(c.o.SourceTarget); err != nil {
+		return
+	}
+
+	record := helpers.PromotionRecord{
+		Time:  time.Now().UTC(),
+		User:  c.destCCSession.GetSessionUsername(),
+		Chain: c.o.Promotion.Chain,
+		From:  c.o.Promotion.From,
+		To:    c.o.Promotion.To,
+	}
+	for _, name := range c.o.SourceAppNames {
+		app := helpers.PromotedApp{Name: name}
+		if srcApp, ok := c.srcApp(name); ok {
+			var droplet v3Droplet
+			if err = ccClient.Do("GET", fmt.Sprintf("/v3/apps/%s/droplets/current", srcApp.GUID), nil, &droplet); err != nil {
+				return
+			}
+			app.DropletGUID = droplet.GUID
+			app.DropletChecksum = droplet.Checksum.Value
+		}
+		record.Apps = append(record.Apps, app)
+	}
+	if err = helpers.AppendPromotionRecord(c.o.Promotion.HistoryPath, record); err != nil {
+		return
+	}
+
+	c.logger.UI.Say("Recorded promotion of %d apps from stage %s to stage %s in %s.", len(record.Apps),
+		terminal.EntityNameColor(record.From), terminal.EntityNameColor(record.To), c.o.Promotion.HistoryPath)
+	return
+}
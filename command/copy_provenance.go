@@ -0,0 +1,122 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// annotateCopies - Records the source of each copied application and
+// service in annotations of the copy so that copies which are out of
+// date with their source can be reported by copy-status
+func (c *CopyCommand) annotateCopies() (err error) {
+
+	var (
+		srcClient  *helpers.CCClient
+		destClient *helpers.CCClient
+
+		srcServices  []models.ServiceInstance
+		destServices []models.ServiceInstance
+	)
+
+	if srcClient, err = c.newCCClient(c.o.SourceTarget); err != nil {
+		return
+	}
+	if destClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+		return
+	}
+	copiedAt := time.Now().UTC()
+
+	if !c.o.ServicesOnly {
+		if err = c.annotateApplications(srcClient, destClient, copiedAt); err != nil {
+			return
+		}
+	}
+
+	if srcServices, err = c.srcCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	if destServices, err = c.destCCSession.ServiceSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	// Services skipped on a conflict are kept at the destination as they are
+	skipped := c.serviceFilter.Excluded()
+	for _, s := range srcServices {
+		if !c.isServiceCopied(s) || containsString(skipped, s.Name) {
+			continue
+		}
+		for _, d := range destServices {
+			if d.Name != s.Name {
+				continue
+			}
+			p := c.provenance(copiedAt)
+			p.SourceService = s.Name
+			p.SourceGUID = s.GUID
+			if err = annotate(destClient, "/v3/service_instances/"+d.GUID, p.Annotations()); err != nil {
+				return
+			}
+			break
+		}
+	}
+	return
+}
+
+// annotateApplications - Records the source and the source droplet
+// of each copied application in annotations of its copy
+func (c *CopyCommand) annotateApplications(srcClient, destClient *helpers.CCClient, copiedAt time.Time) (err error) {
+
+	var destApps []models.Application
+
+	if destApps, err = c.destCCSession.AppSummary().GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	for _, name := range c.o.SourceAppNames {
+		srcApp, ok := c.srcApp(name)
+		if !ok {
+			continue
+		}
+		for _, d := range destApps {
+			if d.Name != name {
+				continue
+			}
+
+			var droplet v3Droplet
+
+			p := c.provenance(copiedAt)
+			p.SourceApp = name
+			p.SourceGUID = srcApp.GUID
+			if err = srcClient.Do("GET", fmt.Sprintf("/v3/apps/%s/droplets/current", srcApp.GUID), nil, &droplet); err != nil {
+				// Apps that have not been staged have no droplet
+				c.logger.DebugMessage("No current droplet of source app '%s': %s", name, err.Error())
+			}
+			p.SourceDroplet = droplet.GUID
+			if err = annotate(destClient, "/v3/apps/"+d.GUID, p.Annotations()); err != nil {
+				return
+			}
+			break
+		}
+	}
+	return
+}
+
+// provenance - Returns the provenance of the copies made at the given time
+func (c *CopyCommand) provenance(copiedAt time.Time) helpers.Provenance {
+	return helpers.Provenance{
+		SourceTarget: c.o.SourceTarget,
+		SourceOrg:    c.srcOrg.Name,
+		SourceSpace:  c.srcSpace.Name,
+		CopiedAt:     copiedAt,
+	}
+}
+
+// annotate - Sets the given annotations of the v3 resource with the given path
+func annotate(ccClient *helpers.CCClient, path string, annotations map[string]string) error {
+	request := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	}
+	return ccClient.Do("PATCH", path, request, nil)
+}
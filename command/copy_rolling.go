@@ -0,0 +1,309 @@
+package command
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// StrategyRolling - Rolls out copies of existing destination
+// applications using Cloud Controller v3 deployments
+const StrategyRolling = "rolling"
+
+type v3Relationships struct {
+	App struct {
+		Data struct {
+			GUID string `json:"guid"`
+		} `json:"data"`
+	} `json:"app"`
+}
+
+type v3Droplet struct {
+	GUID     string `json:"guid"`
+	State    string `json:"state"`
+	Checksum struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"checksum"`
+}
+
+type v3Deployment struct {
+	GUID   string `json:"guid"`
+	State  string `json:"state"`
+	Status struct {
+		Value  string `json:"value"`
+		Reason string `json:"reason"`
+	} `json:"status"`
+}
+
+// isDone - Supports the deployment status of newer Cloud Controllers
+// as well as the deprecated state of earlier v3 API versions
+func (d v3Deployment) isDone() bool {
+	return d.Status.Value == "FINALIZED" || d.State == "DEPLOYED" || d.State == "CANCELED"
+}
+
+func (d v3Deployment) isDeployed() bool {
+	return d.Status.Reason == "DEPLOYED" || d.State == "DEPLOYED"
+}
+
+func (d v3Deployment) progress() string {
+	if d.Status.Reason != "" {
+		return d.Status.Reason
+	}
+	return d.State
+}
+
+// completeRolling - Rolls out the droplet of each copied application
+// to the existing destination application it replaces using a v3
+// deployment. The intermediate copy is started without routes so that
+// it is staged without serving traffic. Its settings are carried over
+// so the deployment starts the existing application with them. The
+// intermediate copy is then deleted.
+func (c *CopyCommand) completeRolling() (err error) {
+
+	var (
+		ccClient *helpers.CCClient
+		newApp   models.Application
+		droplet  v3Droplet
+	)
+
+	if c.o.Strategy != StrategyRolling || len(c.blueGreenApps) == 0 {
+		return
+	}
+	if ccClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+		return
+	}
+
+	stopped := "stopped"
+	for len(c.blueGreenApps) > 0 {
+		bg := c.blueGreenApps[0]
+
+		if newApp, err = c.destCCSession.Applications().Read(bg.name); err != nil {
+			return
+		}
+		if droplet, err = c.waitForCurrentDroplet(ccClient, newApp.GUID); err != nil {
+			return
+		}
+		if _, err = c.destCCSession.Applications().Update(newApp.GUID, models.AppParams{State: &stopped}); err != nil {
+			return
+		}
+		if err = c.carryOverSettings(ccClient, newApp, bg.oldApp); err != nil {
+			return
+		}
+
+		c.logger.UI.Say("Copying droplet of app %s to existing app...", terminal.EntityNameColor(bg.name))
+		if droplet, err = c.copyDroplet(ccClient, droplet.GUID, bg.oldApp.GUID); err != nil {
+			return
+		}
+		if err = c.deploy(ccClient, bg, droplet.GUID); err != nil {
+			return
+		}
+
+		c.logger.UI.Say("Deleting intermediate copy %s of app %s...",
+			terminal.EntityNameColor(bg.newName), terminal.EntityNameColor(bg.name))
+		if err = c.destCCSession.Applications().Delete(newApp.GUID); err != nil {
+			return
+		}
+		c.blueGreenApps = c.blueGreenApps[1:]
+	}
+	return
+}
+
+// isRollingCopy - Returns whether the copy of the application with the
+// given name is an intermediate copy rolled out to an existing application
+func (c *CopyCommand) isRollingCopy(name string) bool {
+	return c.o.Strategy == StrategyRolling && c.blueGreenName(name) != name
+}
+
+// unmapRoutes - Unmaps all routes from the given intermediate copy so
+// that it does not serve the traffic of the application it is rolled
+// out to when it is started to be staged
+func (c *CopyCommand) unmapRoutes(ccClient *helpers.CCClient, app models.Application) (err error) {
+
+	path := fmt.Sprintf("/v2/apps/%s/routes?results-per-page=100", app.GUID)
+	routeGUIDs := []string{}
+	for path != "" {
+		page := struct {
+			NextURL   string `json:"next_url"`
+			Resources []struct {
+				Metadata struct {
+					GUID string `json:"guid"`
+				} `json:"metadata"`
+			} `json:"resources"`
+		}{}
+		if err = ccClient.Do("GET", path, nil, &page); err != nil {
+			return
+		}
+		for _, r := range page.Resources {
+			routeGUIDs = append(routeGUIDs, r.Metadata.GUID)
+		}
+		path = page.NextURL
+	}
+	for _, routeGUID := range routeGUIDs {
+		c.logger.DebugMessage("Unmapping route '%s' from intermediate copy '%s'", routeGUID, app.Name)
+		if err = c.destCCSession.Routes().Unbind(routeGUID, app.GUID); err != nil {
+			return
+		}
+	}
+	return
+}
+
+type v2ServiceBinding struct {
+	Entity struct {
+		ServiceInstanceGUID string `json:"service_instance_guid"`
+	} `json:"entity"`
+}
+
+// carryOverSettings - Updates the existing application with the
+// environment and scale of its intermediate copy and binds it to the
+// services the copy is bound to that it is not yet bound to
+func (c *CopyCommand) carryOverSettings(ccClient *helpers.CCClient, newApp, oldApp models.Application) (err error) {
+
+	var newBindings, oldBindings map[string]bool
+
+	env := newApp.EnvironmentVars
+	params := models.AppParams{
+		EnvironmentVars: &env,
+		InstanceCount:   &newApp.InstanceCount,
+		Memory:          &newApp.Memory,
+		DiskQuota:       &newApp.DiskQuota,
+	}
+	if _, err = c.destCCSession.Applications().Update(oldApp.GUID, params); err != nil {
+		return
+	}
+
+	if newBindings, err = c.serviceBindings(ccClient, newApp.GUID); err != nil {
+		return
+	}
+	if oldBindings, err = c.serviceBindings(ccClient, oldApp.GUID); err != nil {
+		return
+	}
+	for serviceGUID := range newBindings {
+		if oldBindings[serviceGUID] {
+			continue
+		}
+		c.logger.DebugMessage("Binding service instance '%s' to app '%s'", serviceGUID, oldApp.Name)
+		if err = c.destCCSession.ServiceBindings().Create(serviceGUID, oldApp.GUID, nil); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// serviceBindings - Returns the GUIDs of the service
+// instances the given application is bound to
+func (c *CopyCommand) serviceBindings(ccClient *helpers.CCClient, appGUID string) (serviceGUIDs map[string]bool, err error) {
+
+	serviceGUIDs = make(map[string]bool)
+
+	path := fmt.Sprintf("/v2/apps/%s/service_bindings?results-per-page=100", appGUID)
+	for path != "" {
+		page := struct {
+			NextURL   string             `json:"next_url"`
+			Resources []v2ServiceBinding `json:"resources"`
+		}{}
+		if err = ccClient.Do("GET", path, nil, &page); err != nil {
+			return
+		}
+		for _, b := range page.Resources {
+			serviceGUIDs[b.Entity.ServiceInstanceGUID] = true
+		}
+		path = page.NextURL
+	}
+	return
+}
+
+// waitForCurrentDroplet - Waits for the given application to be staged
+// and returns its current droplet
+func (c *CopyCommand) waitForCurrentDroplet(ccClient *helpers.CCClient, appGUID string) (droplet v3Droplet, err error) {
+
+	deadline := time.Now().Add(c.startTimeout())
+	for {
+		err = ccClient.Do("GET", fmt.Sprintf("/v3/apps/%s/droplets/current", appGUID), nil, &droplet)
+		if err == nil && droplet.State == "STAGED" {
+			return
+		}
+		if ccErr, ok := err.(*helpers.CCError); err != nil && (!ok || ccErr.StatusCode != 404) {
+			return
+		}
+		if time.Now().After(deadline) {
+			err = fmt.Errorf("Timed out waiting for the copied app to be staged.")
+			return
+		}
+		time.Sleep(healthPollInterval)
+	}
+}
+
+// copyDroplet - Copies a droplet to the given application
+func (c *CopyCommand) copyDroplet(ccClient *helpers.CCClient, dropletGUID, appGUID string) (droplet v3Droplet, err error) {
+
+	request := struct {
+		Relationships v3Relationships `json:"relationships"`
+	}{}
+	request.Relationships.App.Data.GUID = appGUID
+
+	if err = ccClient.Do("POST", "/v3/droplets?source_guid="+dropletGUID, request, &droplet); err != nil {
+		return
+	}
+	for droplet.State != "STAGED" {
+		if droplet.State == "FAILED" || droplet.State == "EXPIRED" {
+			err = fmt.Errorf("Copying droplet '%s' failed with state %s.", dropletGUID, droplet.State)
+			return
+		}
+		time.Sleep(healthPollInterval)
+		if err = ccClient.Do("GET", "/v3/droplets/"+droplet.GUID, nil, &droplet); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// deploy - Creates a deployment of the given droplet to the application
+// being replaced and reports its progress until it is done. The
+// deployment is cancelled if it does not complete in time.
+func (c *CopyCommand) deploy(ccClient *helpers.CCClient, bg blueGreenApp, dropletGUID string) (err error) {
+
+	var deployment v3Deployment
+
+	request := struct {
+		Droplet struct {
+			GUID string `json:"guid"`
+		} `json:"droplet"`
+		Relationships v3Relationships `json:"relationships"`
+	}{}
+	request.Droplet.GUID = dropletGUID
+	request.Relationships.App.Data.GUID = bg.oldApp.GUID
+
+	c.logger.UI.Say("Starting rolling deployment of app %s...", terminal.EntityNameColor(bg.name))
+	if err = ccClient.Do("POST", "/v3/deployments", request, &deployment); err != nil {
+		return
+	}
+
+	progress := ""
+	deadline := time.Now().Add(c.startTimeout())
+	for !deployment.isDone() {
+		if deployment.progress() != progress {
+			progress = deployment.progress()
+			c.logger.UI.Say("  deployment %s", terminal.EntityNameColor(progress))
+		}
+		if time.Now().After(deadline) {
+			c.logger.UI.Say("Cancelling deployment of app %s...", terminal.EntityNameColor(bg.name))
+			if err = ccClient.Do("POST", fmt.Sprintf("/v3/deployments/%s/actions/cancel", deployment.GUID), nil, nil); err != nil {
+				return
+			}
+			return fmt.Errorf("Timed out waiting for the deployment of app '%s'. The deployment was cancelled.", bg.name)
+		}
+		time.Sleep(healthPollInterval)
+		if err = ccClient.Do("GET", "/v3/deployments/"+deployment.GUID, nil, &deployment); err != nil {
+			return
+		}
+	}
+	if !deployment.isDeployed() {
+		return fmt.Errorf("The deployment of app '%s' was cancelled.", bg.name)
+	}
+	c.logger.UI.Say("  deployment %s", terminal.EntityNameColor(deployment.progress()))
+	return
+}
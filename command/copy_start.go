@@ -0,0 +1,424 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/models"
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+const (
+	defaultStartTimeout = 5 * time.Minute
+	healthPollInterval  = 5 * time.Second
+)
+
+// startsInTiers - Returns whether the copied applications
+// are started in the order given by the copy options
+func (c *CopyCommand) startsInTiers() bool {
+	return c.o.StartOrderAuto || len(c.o.StartTiers) > 0
+}
+
+// startsApp - Returns whether the copy of the application with the
+// given name is started, which is the case if the source application
+// is started and --no-start is not given
+func (c *CopyCommand) startsApp(name string) bool {
+	srcApp, ok := c.srcApp(name)
+	return !c.o.NoStart && ok && strings.EqualFold(srcApp.State, "started")
+}
+
+// startDestApplications - The copied applications are created stopped
+// so that they are started only once the copy is done. Applications
+// started at the source are started unless --no-start is given. When a
+// start order is given the copied applications that are started are
+// started tier by tier waiting for each tier to be healthy before
+// starting the next. The names of the started applications are returned.
+func (c *CopyCommand) startDestApplications() (started []string, err error) {
+
+	var tiers [][]string
+
+	if c.o.NoStart {
+		return
+	}
+
+	if !c.startsInTiers() {
+		for _, name := range c.o.SourceAppNames {
+			if c.startsApp(name) {
+				if err = c.startDestApplication(name); err != nil {
+					return
+				}
+				started = append(started, name)
+			}
+		}
+		return
+	}
+
+	if c.o.StartOrderAuto {
+		var dependsOn map[string][]string
+		if dependsOn, err = c.srcAppDependencies(); err != nil {
+			return
+		}
+		if tiers, err = helpers.DependencyTiers(c.o.SourceAppNames, dependsOn); err != nil {
+			return
+		}
+	} else {
+		tiers = helpers.OrderStartTiers(c.o.SourceAppNames, c.o.StartTiers)
+	}
+	tiers = c.startedTiers(tiers)
+	c.logger.DebugMessage("Application start tiers => %# v", tiers)
+
+	for i, tier := range tiers {
+
+		c.logger.UI.Say("Starting apps %s in tier %d...",
+			terminal.EntityNameColor(strings.Join(tier, ", ")), i+1)
+
+		for _, name := range tier {
+			if err = c.startDestApplication(name); err != nil {
+				return
+			}
+			started = append(started, name)
+		}
+		var health []appHealth
+		if health, err = c.waitForApplications(tier, c.startTimeout()); err != nil {
+			return
+		}
+		for _, h := range health {
+			if !h.isHealthy() {
+				return fmt.Errorf("Timed out waiting for app '%s' to start.", h.name)
+			}
+		}
+	}
+	return
+}
+
+// startedTiers - Returns the given start tiers with only the applications
+// that are started so that the start order only changes the order in
+// which the applications are started. Tiers left empty are dropped.
+func (c *CopyCommand) startedTiers(tiers [][]string) (started [][]string) {
+	for _, tier := range tiers {
+		names := []string{}
+		for _, name := range tier {
+			if c.startsApp(name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			started = append(started, names)
+		}
+	}
+	return
+}
+
+// startDestApplication - Releases the copied application with the given
+// name from the start gate and starts it. The intermediate copy of a
+// rolling replacement is started without routes.
+func (c *CopyCommand) startDestApplication(name string) (err error) {
+
+	var app models.Application
+
+	c.beginAppStep(name, "start")
+	if app, err = c.destCCSession.Applications().Read(name); err != nil {
+		return
+	}
+	if c.isRollingCopy(name) {
+		var ccClient *helpers.CCClient
+
+		if ccClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+			return
+		}
+		if err = c.unmapRoutes(ccClient, app); err != nil {
+			return
+		}
+	}
+	c.startGate.Release(app.GUID)
+
+	started := "started"
+	_, err = c.destCCSession.Applications().Update(app.GUID, models.AppParams{State: &started})
+	return
+}
+
+// appHealth - The instance states of a destination application
+type appHealth struct {
+	name      string
+	guid      string
+	instances []models.AppInstanceFields
+}
+
+// isHealthy - An application is healthy once all its instances are running
+func (h appHealth) isHealthy() bool {
+	if len(h.instances) == 0 {
+		return false
+	}
+	for _, i := range h.instances {
+		if i.State != models.InstanceRunning {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForApplications - Polls the instances of the given destination
+// applications until all instances are running or the timeout expires
+// and returns the last known health of each application
+func (c *CopyCommand) waitForApplications(names []string, timeout time.Duration) (health []appHealth, err error) {
+
+	var app models.Application
+
+	guids := make(map[string]string)
+	for _, name := range names {
+		if app, err = c.destCCSession.Applications().Read(name); err != nil {
+			return
+		}
+		guids[name] = app.GUID
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		health = []appHealth{}
+		pending := []string{}
+
+		for _, name := range names {
+			h := appHealth{name: name, guid: guids[name]}
+			if h.instances, err = c.destCCSession.AppInstances().GetInstances(guids[name]); err != nil {
+				// Instances cannot be retrieved while an app is staging
+				c.logger.DebugMessage("Unable to retrieve instances of app '%s': %s", name, err.Error())
+				err = nil
+			}
+			if !h.isHealthy() {
+				pending = append(pending, name)
+			}
+			health = append(health, h)
+		}
+		if len(pending) == 0 || time.Now().After(deadline) {
+			return
+		}
+		c.logger.DebugMessage("Waiting for apps to start => %# v", pending)
+		time.Sleep(healthPollInterval)
+	}
+}
+
+// reportApplicationHealth - Waits for the given started applications to
+// be healthy and outputs the state of each application's instances
+// along with the reason of any instances that have crashed
+func (c *CopyCommand) reportApplicationHealth(names []string) (healthy bool, err error) {
+
+	var (
+		ccClient *helpers.CCClient
+		health   []appHealth
+	)
+
+	if len(names) == 0 {
+		return true, nil
+	}
+	c.logger.UI.Say("Waiting for apps to start...")
+	if health, err = c.waitForApplications(names, c.startTimeout()); err != nil {
+		return
+	}
+	if ccClient, err = c.newCCClient(c.o.DestTarget); err != nil {
+		return
+	}
+
+	healthy = true
+	c.logger.UI.Say("")
+
+	table := c.logger.UI.Table([]string{"app", "state", "instances", "details"})
+	for _, h := range health {
+
+		var reasons map[int]string
+
+		running := 0
+		details := []string{}
+		for i, instance := range h.instances {
+			if instance.State == models.InstanceRunning {
+				running++
+			} else if instance.State == models.InstanceCrashed || instance.State == models.InstanceFlapping {
+				if reasons == nil {
+					if reasons, err = crashReasons(ccClient, h.guid); err != nil {
+						c.logger.DebugMessage("Unable to retrieve crash events of app '%s': %s", h.name, err.Error())
+						reasons, err = map[int]string{}, nil
+					}
+				}
+				reason, ok := reasons[i]
+				if !ok {
+					reason = "unknown"
+				}
+				details = append(details, fmt.Sprintf("#%d %s: %s", i, instance.State, reason))
+			}
+		}
+
+		state := "healthy"
+		if !h.isHealthy() {
+			state = "unhealthy"
+			healthy = false
+		}
+		table.Add(h.name, state, fmt.Sprintf("%d/%d", running, len(h.instances)), strings.Join(details, ", "))
+	}
+	table.Print()
+	c.logger.UI.Say("")
+	return
+}
+
+// crashReasons - Returns the reason of the most recent crash of each
+// instance of the given application read from its crash events
+func crashReasons(ccClient *helpers.CCClient, appGUID string) (reasons map[int]string, err error) {
+
+	var events struct {
+		Resources []struct {
+			Entity struct {
+				Metadata struct {
+					Index           int    `json:"index"`
+					Reason          string `json:"reason"`
+					ExitDescription string `json:"exit_description"`
+				} `json:"metadata"`
+			} `json:"entity"`
+		} `json:"resources"`
+	}
+
+	if err = ccClient.Do("GET", fmt.Sprintf("/v2/events?q=actee:%s&q=type:app.crash&order-direction=desc&results-per-page=100",
+		appGUID), nil, &events); err != nil {
+		return
+	}
+	reasons = make(map[int]string)
+	for _, e := range events.Resources {
+		m := e.Entity.Metadata
+		if _, ok := reasons[m.Index]; ok {
+			continue
+		}
+		reason := m.ExitDescription
+		if reason == "" {
+			reason = m.Reason
+		}
+		if reason != "" {
+			reasons[m.Index] = reason
+		}
+	}
+	return
+}
+
+// startTimeout - Returns how long to wait for copied applications to start
+func (c *CopyCommand) startTimeout() time.Duration {
+	if c.o.Timeout > 0 {
+		return c.o.Timeout
+	}
+	return defaultStartTimeout
+}
+
+// srcAppDependencies - Derives the dependencies between the source
+// applications from container to container network policies and
+// from user provided services whose credentials reference the
+// routes of other applications in the source space
+func (c *CopyCommand) srcAppDependencies() (dependsOn map[string][]string, err error) {
+
+	var (
+		ccClient *helpers.CCClient
+
+		summary struct {
+			Apps []struct {
+				GUID   string `json:"guid"`
+				Name   string `json:"name"`
+				Routes []struct {
+					Host   string `json:"host"`
+					Domain struct {
+						Name string `json:"name"`
+					} `json:"domain"`
+				} `json:"routes"`
+				ServiceNames []string `json:"service_names"`
+			} `json:"apps"`
+		}
+		upsInstances []v2UserProvidedService
+		policies     struct {
+			Policies []struct {
+				Source struct {
+					ID string `json:"id"`
+				} `json:"source"`
+				Destination struct {
+					ID string `json:"id"`
+				} `json:"destination"`
+			} `json:"policies"`
+		}
+	)
+
+	if ccClient, err = c.newCCClient(c.o.SourceTarget); err != nil {
+		return
+	}
+	dependsOn = make(map[string][]string)
+
+	if err = ccClient.Do("GET", fmt.Sprintf("/v2/spaces/%s/summary", c.srcSpace.GUID), nil, &summary); err != nil {
+		return
+	}
+	appNames := make(map[string]string)
+	for _, a := range summary.Apps {
+		appNames[a.GUID] = a.Name
+	}
+
+	// Container to container policies allow the source app to call the destination app
+	if err = ccClient.Do("GET", "/networking/v1/external/policies", nil, &policies); err != nil {
+		c.logger.DebugMessage("Unable to retrieve network policies: %s", err.Error())
+		err = nil
+	}
+	for _, p := range policies.Policies {
+		consumer, okSrc := appNames[p.Source.ID]
+		backend, okDest := appNames[p.Destination.ID]
+		if okSrc && okDest {
+			dependsOn[consumer] = append(dependsOn[consumer], backend)
+		}
+	}
+
+	// User provided services referencing another app's route
+	if upsInstances, err = listUserProvidedServices(ccClient, c.srcSpace.GUID); err != nil {
+		return
+	}
+	upsBackends := make(map[string][]string)
+	for _, r := range upsInstances {
+		credentials, _ := json.Marshal(r.Entity.Credentials)
+		for _, a := range summary.Apps {
+			for _, route := range a.Routes {
+				hostname := route.Domain.Name
+				if route.Host != "" {
+					hostname = route.Host + "." + hostname
+				}
+				if strings.Contains(string(credentials), hostname) {
+					upsBackends[r.Entity.Name] = append(upsBackends[r.Entity.Name], a.Name)
+					break
+				}
+			}
+		}
+	}
+	for _, a := range summary.Apps {
+		for _, s := range a.ServiceNames {
+			dependsOn[a.Name] = append(dependsOn[a.Name], upsBackends[s]...)
+		}
+	}
+
+	c.logger.DebugMessage("Application dependencies => %# v", dependsOn)
+	return
+}
+
+type v2UserProvidedService struct {
+	Entity struct {
+		Name        string                 `json:"name"`
+		Credentials map[string]interface{} `json:"credentials"`
+	} `json:"entity"`
+}
+
+// listUserProvidedServices - Returns the user provided services of the
+// given space following the pages of the v2 API until the last page
+func listUserProvidedServices(ccClient *helpers.CCClient, spaceGUID string) (services []v2UserProvidedService, err error) {
+
+	path := fmt.Sprintf("/v2/user_provided_service_instances?q=space_guid:%s&results-per-page=100", spaceGUID)
+	for path != "" {
+		var page struct {
+			NextURL   string                  `json:"next_url"`
+			Resources []v2UserProvidedService `json:"resources"`
+		}
+		if err = ccClient.Do("GET", path, nil, &page); err != nil {
+			return
+		}
+		services = append(services, page.Resources...)
+		path = page.NextURL
+	}
+	return
+}
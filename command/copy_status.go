@@ -0,0 +1,243 @@
+package command
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/terminal"
+	plugin_models "code.cloudfoundry.org/cli/plugin/models"
+	"github.com/mevansam/cf-copy-plugin/helpers"
+)
+
+// Statuses of copies reported by copy-status
+const (
+	statusUpToDate      = "up to date"
+	statusOutOfDate     = "out of date"
+	statusSourceDeleted = "source deleted"
+	statusUnknown       = "unknown"
+)
+
+type v3AnnotatedResource struct {
+	v3Resource
+	UpdatedAt time.Time `json:"updated_at"`
+	Metadata  struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// copyStatus - The status of a copy in the space the CLI is targeted at
+type copyStatus struct {
+	name       string
+	kind       string
+	provenance helpers.Provenance
+	status     string
+	reason     string
+}
+
+// sourceLookup - Looks up the sources of copies. The clients of
+// the source targets and the source spaces found are cached.
+type sourceLookup struct {
+	c *CopyCommand
+
+	clients map[string]*helpers.CCClient
+	spaces  map[string]string
+}
+
+// reportCopyStatus - Reports the apps and services of the space the
+// CLI is targeted at which are copies whose source has changed since
+// they were copied. The source of a copy is read from the annotations
+// added to it by the copy.
+func (c *CopyCommand) reportCopyStatus(o *CopyOptions) int {
+
+	var (
+		err           error
+		currentTarget string
+		space         plugin_models.Space
+		ccClient      *helpers.CCClient
+
+		apps     []v3AnnotatedResource
+		services []v3AnnotatedResource
+	)
+
+	c.o = o
+
+	if currentTarget, err = c.resolveTargets(); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitTargetError
+	}
+	if space, err = c.cli.GetCurrentSpace(); err != nil || space.Guid == "" {
+		c.logger.UI.Failed("The CLI target org and space needs to be set.")
+		return ExitTargetError
+	}
+	if o.Retries > 0 {
+		c.retry = helpers.NewRetryPolicy(o.Retries, c.logger.DebugMessage)
+	}
+	if ccClient, err = c.newCCClient(currentTarget); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitTargetError
+	}
+
+	if err = listV3(ccClient, "/v3/apps?space_guids="+space.Guid, &apps, nil); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitTotalFailure
+	}
+	if err = listV3(ccClient, "/v3/service_instances?space_guids="+space.Guid, &services, nil); err != nil {
+		c.logger.UI.Failed(err.Error())
+		return ExitTotalFailure
+	}
+
+	lookup := &sourceLookup{
+		c:       c,
+		clients: map[string]*helpers.CCClient{currentTarget: ccClient},
+		spaces:  make(map[string]string),
+	}
+	statuses := []copyStatus{}
+	for _, r := range append(apps, services...) {
+		if p, ok := helpers.ParseProvenance(r.Metadata.Annotations); ok {
+			cs := copyStatus{name: r.Name, kind: "app", provenance: p}
+			if p.SourceService != "" {
+				cs.kind = "service"
+			}
+			cs.status, cs.reason = lookup.check(p)
+			statuses = append(statuses, cs)
+		}
+	}
+
+	if len(statuses) == 0 {
+		c.logger.UI.Say("No copies were found in space %s.", terminal.EntityNameColor(space.Name))
+		return ExitOK
+	}
+
+	outOfDate := 0
+	c.logger.UI.Say("")
+	table := c.logger.UI.Table([]string{"name", "kind", "source", "copied", "status"})
+	for _, cs := range statuses {
+		status := cs.status
+		if cs.reason != "" {
+			status += ": " + cs.reason
+		}
+		switch cs.status {
+		case statusUpToDate:
+			status = terminal.SuccessColor(status)
+		case statusOutOfDate, statusSourceDeleted:
+			status = terminal.FailureColor(status)
+			outOfDate++
+		}
+		copied := ""
+		if !cs.provenance.CopiedAt.IsZero() {
+			copied = cs.provenance.CopiedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		table.Add(cs.name, cs.kind, cs.provenance.Source(), copied, status)
+	}
+	table.Print()
+	c.logger.UI.Say("")
+
+	if outOfDate > 0 {
+		c.logger.UI.Say("%d of %d copies are out of date with their source.", outOfDate, len(statuses))
+		return ExitOutOfDate
+	}
+	c.logger.UI.Ok()
+	return ExitOK
+}
+
+// check - Compares the copy with the given provenance with its source
+// and returns its status and the reason the copy is out of date
+func (l *sourceLookup) check(p helpers.Provenance) (status, reason string) {
+
+	var (
+		err     error
+		found   bool
+		source  v3AnnotatedResource
+		droplet v3Droplet
+	)
+
+	ccClient, spaceGUID, err := l.space(p)
+	if err != nil {
+		return statusUnknown, err.Error()
+	}
+	if spaceGUID == "" {
+		return statusSourceDeleted, "the source space does not exist"
+	}
+
+	if p.SourceApp != "" {
+		found, err = findV3(ccClient, fmt.Sprintf("/v3/apps?names=%s&space_guids=%s", url.QueryEscape(p.SourceApp), spaceGUID), &source)
+	} else {
+		found, err = findV3(ccClient, fmt.Sprintf("/v3/service_instances?names=%s&space_guids=%s", url.QueryEscape(p.SourceService), spaceGUID), &source)
+	}
+	switch {
+	case err != nil:
+		return statusUnknown, err.Error()
+	case !found:
+		return statusSourceDeleted, ""
+	case p.SourceGUID != "" && source.GUID != p.SourceGUID:
+		return statusOutOfDate, "the source was recreated"
+	}
+
+	if p.SourceApp == "" {
+		if source.UpdatedAt.After(p.CopiedAt) {
+			return statusOutOfDate, "the source was updated"
+		}
+		return statusUpToDate, ""
+	}
+	if err = ccClient.Do("GET", fmt.Sprintf("/v3/apps/%s/droplets/current", source.GUID), nil, &droplet); err != nil {
+		if ccErr, ok := err.(*helpers.CCError); !ok || ccErr.StatusCode != 404 {
+			return statusUnknown, err.Error()
+		}
+	}
+	if droplet.GUID != p.SourceDroplet {
+		return statusOutOfDate, "the source droplet changed"
+	}
+	return statusUpToDate, ""
+}
+
+// space - Returns the client of the source target of the copy with the
+// given provenance and the GUID of its source space which is empty if
+// the space does not exist
+func (l *sourceLookup) space(p helpers.Provenance) (ccClient *helpers.CCClient, spaceGUID string, err error) {
+
+	var (
+		ok    bool
+		found bool
+		org   v3AnnotatedResource
+		space v3AnnotatedResource
+	)
+
+	if ccClient, ok = l.clients[p.SourceTarget]; !ok {
+		if !l.c.targets.HasTarget(p.SourceTarget) {
+			return nil, "", fmt.Errorf("the target '%s' is not saved", p.SourceTarget)
+		}
+		if ccClient, err = l.c.newCCClient(p.SourceTarget); err != nil {
+			return
+		}
+		l.clients[p.SourceTarget] = ccClient
+	}
+
+	key := p.SourceTarget + "/" + p.SourceOrg + "/" + p.SourceSpace
+	if spaceGUID, ok = l.spaces[key]; ok {
+		return
+	}
+	if found, err = findV3(ccClient, "/v3/organizations?names="+url.QueryEscape(p.SourceOrg), &org); err != nil || !found {
+		return
+	}
+	if found, err = findV3(ccClient, fmt.Sprintf("/v3/spaces?names=%s&organization_guids=%s",
+		url.QueryEscape(p.SourceSpace), org.GUID), &space); err != nil || !found {
+		return
+	}
+	spaceGUID = space.GUID
+	l.spaces[key] = spaceGUID
+	return
+}
+
+// findV3 - Unmarshals the only resource listed by the given
+// path into the given resource and returns whether it exists
+func findV3(ccClient *helpers.CCClient, path string, resource *v3AnnotatedResource) (bool, error) {
+
+	var resources []v3AnnotatedResource
+
+	if err := listV3(ccClient, path, &resources, nil); err != nil || len(resources) == 0 {
+		return false, err
+	}
+	*resource = resources[0]
+	return true, nil
+}
@@ -21,10 +21,31 @@ func NewMockCopyCommand(validate func(o *command.CopyOptions)) *MockCopyCommand
 }
 
 // Execute -
-func (m MockCopyCommand) Execute(cli plugin.CliConnection, o *command.CopyOptions) {
+func (m MockCopyCommand) Execute(cli plugin.CliConnection, o *command.CopyOptions) int {
 	m.validate(o)
 
 	logger := trace.NewLogger(os.Stdout, true, "", "")
 	ui := terminal.NewUI(os.Stdin, os.Stdout, terminal.NewTeePrinter(os.Stdout), logger)
 	ui.Say("Done")
+	return 0
+}
+
+// MockCopyCommandFactory -
+type MockCopyCommandFactory struct {
+	run func(o *command.CopyOptions) int
+}
+
+// NewMockCopyCommandFactory -
+func NewMockCopyCommandFactory(run func(o *command.CopyOptions) int) *MockCopyCommandFactory {
+	return &MockCopyCommandFactory{run}
+}
+
+// NewCopy -
+func (m *MockCopyCommandFactory) NewCopy() command.CopyCmd {
+	return &MockCopyCommandFactory{m.run}
+}
+
+// Execute -
+func (m *MockCopyCommandFactory) Execute(cli plugin.CliConnection, o *command.CopyOptions) int {
+	return m.run(o)
 }
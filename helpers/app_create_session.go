@@ -0,0 +1,147 @@
+package helpers
+
+import (
+	"strings"
+	"sync"
+
+	"code.cloudfoundry.org/cli/cf/api/applications"
+	"code.cloudfoundry.org/cli/cf/models"
+	"github.com/mevansam/cf-cli-api/cfapi"
+)
+
+// StartGate - Holds back the starts of the applications created through
+// a session until the applications are released to be started
+type StartGate struct {
+	held  map[string]bool
+	mutex sync.Mutex
+}
+
+// NewStartGate -
+func NewStartGate() *StartGate {
+	return &StartGate{held: make(map[string]bool)}
+}
+
+// Release - Allows the application with the given GUID to be started
+func (g *StartGate) Release(appGUID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.held, appGUID)
+}
+
+func (g *StartGate) hold(appGUID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.held[appGUID] = true
+}
+
+func (g *StartGate) isHeld(appGUID string) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.held[appGUID]
+}
+
+// NewAppCreateSession - Wraps the given session so that the parameters
+// of each application created through it are prepared with the given
+// function before the application is created. The applications are
+// created stopped and are not started until the gate releases them.
+// An application the function gives another name is read by the name
+// it was to be created with until it is renamed.
+func NewAppCreateSession(session cfapi.CfSession, prepare func(params *models.AppParams), gate *StartGate) cfapi.CfSession {
+	return &appCreateSession{
+		CfSession: session,
+		prepare:   prepare,
+		gate:      gate,
+		renamed:   make(map[string]models.Application),
+	}
+}
+
+// appCreateSession - The applications manager creates the copied
+// applications with the settings of their source and starts them
+// as they are copied. Preparing their parameters at creation and
+// holding back their start applies changes made by the copy before
+// the applications are started for the first time.
+type appCreateSession struct {
+	cfapi.CfSession
+	prepare func(params *models.AppParams)
+	gate    *StartGate
+
+	// The applications created with another name
+	// keyed by the name they were to be created with
+	renamed map[string]models.Application
+	mutex   sync.Mutex
+}
+
+func (s *appCreateSession) Applications() applications.Repository {
+	return &appCreateApplications{Repository: s.CfSession.Applications(), session: s}
+}
+
+func (s *appCreateSession) rename(name string, app models.Application) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.renamed[name] = app
+}
+
+func (s *appCreateSession) renamedApp(name string) (app models.Application, ok bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	app, ok = s.renamed[name]
+	return
+}
+
+func (s *appCreateSession) forget(appGUID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for name, app := range s.renamed {
+		if app.GUID == appGUID {
+			delete(s.renamed, name)
+		}
+	}
+}
+
+type appCreateApplications struct {
+	applications.Repository
+	session *appCreateSession
+}
+
+func (r *appCreateApplications) Create(params models.AppParams) (app models.Application, err error) {
+
+	var name string
+
+	if params.Name != nil {
+		name = *params.Name
+	}
+	stopped := "stopped"
+	params.State = &stopped
+	r.session.prepare(&params)
+
+	if app, err = r.Repository.Create(params); err != nil {
+		return
+	}
+	r.session.gate.hold(app.GUID)
+	if params.Name != nil && *params.Name != name {
+		r.session.rename(name, app)
+	}
+	return
+}
+
+func (r *appCreateApplications) Read(name string) (models.Application, error) {
+	if app, ok := r.session.renamedApp(name); ok {
+		return r.Repository.Read(app.Name)
+	}
+	return r.Repository.Read(name)
+}
+
+func (r *appCreateApplications) Update(appGUID string, params models.AppParams) (models.Application, error) {
+	if params.State != nil && strings.EqualFold(*params.State, "started") && r.session.gate.isHeld(appGUID) {
+		params.State = nil
+	}
+	if params.Name != nil {
+		r.session.forget(appGUID)
+	}
+	return r.Repository.Update(appGUID, params)
+}
+
+func (r *appCreateApplications) Delete(appGUID string) error {
+	r.session.forget(appGUID)
+	return r.Repository.Delete(appGUID)
+}
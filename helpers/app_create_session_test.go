@@ -0,0 +1,97 @@
+package helpers_test
+
+import (
+	"code.cloudfoundry.org/cli/cf/api/applications"
+	"code.cloudfoundry.org/cli/cf/api/applications/applicationsfakes"
+	"code.cloudfoundry.org/cli/cf/models"
+	"github.com/mevansam/cf-cli-api/cfapi/mocks"
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("App Create Session Tests", func() {
+
+	var (
+		fakeApplications *applicationsfakes.FakeRepository
+		gate             *StartGate
+		session          applications.Repository
+	)
+
+	BeforeEach(func() {
+		fakeApplications = &applicationsfakes.FakeRepository{}
+		fakeApplications.CreateStub = func(params models.AppParams) (app models.Application, err error) {
+			app.GUID = "fake_app_guid"
+			app.Name = *params.Name
+			return
+		}
+		gate = NewStartGate()
+
+		prepare := func(params *models.AppParams) {
+			env := map[string]interface{}{"LOG_LEVEL": "debug"}
+			params.EnvironmentVars = &env
+			if *params.Name == "api" {
+				newName := "api-copy"
+				params.Name = &newName
+			}
+		}
+		session = NewAppCreateSession(&mocks.MockSession{
+			MockApplications: func() applications.Repository { return fakeApplications },
+		}, prepare, gate).Applications()
+	})
+
+	It("Creates apps stopped with their prepared parameters", func() {
+		name := "web"
+		started := "started"
+		_, err := session.Create(models.AppParams{Name: &name, State: &started})
+		Expect(err).NotTo(HaveOccurred())
+
+		params := fakeApplications.CreateArgsForCall(0)
+		Expect(*params.State).To(Equal("stopped"))
+		Expect(*params.EnvironmentVars).To(Equal(map[string]interface{}{"LOG_LEVEL": "debug"}))
+	})
+
+	It("Holds back the start of created apps until they are released", func() {
+		name := "web"
+		started := "started"
+		_, err := session.Create(models.AppParams{Name: &name})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = session.Update("fake_app_guid", models.AppParams{State: &started})
+		Expect(err).NotTo(HaveOccurred())
+		_, params := fakeApplications.UpdateArgsForCall(0)
+		Expect(params.State).To(BeNil())
+
+		_, err = session.Update("other_app_guid", models.AppParams{State: &started})
+		Expect(err).NotTo(HaveOccurred())
+		_, params = fakeApplications.UpdateArgsForCall(1)
+		Expect(*params.State).To(Equal("started"))
+
+		gate.Release("fake_app_guid")
+		_, err = session.Update("fake_app_guid", models.AppParams{State: &started})
+		Expect(err).NotTo(HaveOccurred())
+		_, params = fakeApplications.UpdateArgsForCall(2)
+		Expect(*params.State).To(Equal("started"))
+	})
+
+	It("Reads apps created with another name by their original name until they are renamed", func() {
+		fakeApplications.ReadStub = func(name string) (app models.Application, err error) {
+			app.Name = name
+			return
+		}
+		name := "api"
+		_, err := session.Create(models.AppParams{Name: &name})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(*fakeApplications.CreateArgsForCall(0).Name).To(Equal("api-copy"))
+
+		app, err := session.Read("api")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app.Name).To(Equal("api-copy"))
+
+		_, err = session.Update("fake_app_guid", models.AppParams{Name: &name})
+		Expect(err).NotTo(HaveOccurred())
+		app, err = session.Read("api")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app.Name).To(Equal("api"))
+	})
+})
@@ -0,0 +1,146 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// AppEnv - Environment variable overrides and filters
+// applied to applications copied to the destination
+type AppEnv struct {
+	global       map[string]string
+	apps         map[string]map[string]string
+	dropPatterns []string
+}
+
+// NewAppEnv -
+func NewAppEnv() *AppEnv {
+	return &AppEnv{
+		global: make(map[string]string),
+		apps:   make(map[string]map[string]string),
+	}
+}
+
+// IsEmpty -
+func (e *AppEnv) IsEmpty() bool {
+	return len(e.global) == 0 && len(e.apps) == 0 && len(e.dropPatterns) == 0
+}
+
+// SetVar - Sets a variable for the given application. If
+// the application name is empty the variable is set for
+// all applications.
+func (e *AppEnv) SetVar(appName, key, value string) {
+	if appName == "" {
+		e.global[key] = value
+		return
+	}
+	vars, exists := e.apps[appName]
+	if !exists {
+		vars = make(map[string]string)
+		e.apps[appName] = vars
+	}
+	vars[key] = value
+}
+
+// ParseVar - Parses a KEY=VALUE pair and sets it for
+// all applications
+func (e *AppEnv) ParseVar(keyValue string) error {
+	key, value, err := splitKeyValue(keyValue)
+	if err != nil {
+		return err
+	}
+	e.SetVar("", key, value)
+	return nil
+}
+
+// ParseFile - Reads KEY=VALUE pairs from the given file. Variables
+// following a '[APP_NAME]' section header are set only for that
+// application. Blank lines and lines starting with '#' are ignored.
+func (e *AppEnv) ParseFile(filePath string) error {
+
+	var (
+		err     error
+		file    *os.File
+		appName string
+	)
+
+	if file, err = os.Open(filePath); err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lineNum := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			appName = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		key, value, err := splitKeyValue(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s", filePath, lineNum, err.Error())
+		}
+		e.SetVar(appName, key, value)
+	}
+	return scanner.Err()
+}
+
+// AddDropPattern - Adds a glob pattern matching the names
+// of variables to be removed from the copied applications
+func (e *AppEnv) AddDropPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid environment variable pattern '%s'", pattern)
+	}
+	e.dropPatterns = append(e.dropPatterns, pattern)
+	return nil
+}
+
+// Apply - Returns a copy of the given application environment with
+// matching variables dropped and the overrides applied. Application
+// specific overrides take precedence over global overrides.
+func (e *AppEnv) Apply(appName string, env map[string]interface{}) map[string]interface{} {
+
+	result := make(map[string]interface{})
+	for k, v := range env {
+		if !e.isDropped(k) {
+			result[k] = v
+		}
+	}
+	for k, v := range e.global {
+		result[k] = v
+	}
+	for k, v := range e.apps[appName] {
+		result[k] = v
+	}
+	return result
+}
+
+func (e *AppEnv) isDropped(key string) bool {
+	for _, p := range e.dropPatterns {
+		if matched, _ := path.Match(p, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func splitKeyValue(keyValue string) (key string, value string, err error) {
+	i := strings.Index(keyValue, "=")
+	if i <= 0 {
+		err = fmt.Errorf("invalid environment variable '%s', expected KEY=VALUE", keyValue)
+		return
+	}
+	key = strings.TrimSpace(keyValue[:i])
+	value = keyValue[i+1:]
+	return
+}
@@ -0,0 +1,87 @@
+package helpers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("App Env Tests", func() {
+
+	var appEnv *AppEnv
+
+	BeforeEach(func() {
+		appEnv = NewAppEnv()
+	})
+
+	It("Drops matching variables and applies the overrides", func() {
+		Expect(appEnv.IsEmpty()).To(BeTrue())
+
+		Expect(appEnv.ParseVar("LOG_LEVEL=debug")).To(Succeed())
+		Expect(appEnv.ParseVar("JAVA_OPTS=-Xmx512m -Dmode=test")).To(Succeed())
+		Expect(appEnv.AddDropPattern("PROD_*")).To(Succeed())
+		appEnv.SetVar("web", "LOG_LEVEL", "info")
+		Expect(appEnv.IsEmpty()).To(BeFalse())
+
+		src := map[string]interface{}{
+			"LOG_LEVEL":    "warn",
+			"PROD_DB_URL":  "postgres://prod",
+			"FEATURE_FLAG": "on",
+		}
+		Expect(appEnv.Apply("api", src)).To(Equal(map[string]interface{}{
+			"LOG_LEVEL":    "debug",
+			"JAVA_OPTS":    "-Xmx512m -Dmode=test",
+			"FEATURE_FLAG": "on",
+		}))
+		Expect(appEnv.Apply("web", src)).To(Equal(map[string]interface{}{
+			"LOG_LEVEL":    "info",
+			"JAVA_OPTS":    "-Xmx512m -Dmode=test",
+			"FEATURE_FLAG": "on",
+		}))
+
+		// The source environment is left as it is
+		Expect(src).To(HaveKey("PROD_DB_URL"))
+	})
+
+	It("Reads global and app specific variables from a file", func() {
+		dir, _ := ioutil.TempDir("", "app-env")
+		defer os.RemoveAll(dir)
+
+		envFile := filepath.Join(dir, "env")
+		Expect(ioutil.WriteFile(envFile, []byte(`
+# Applied to all apps
+REGION=eu-west
+
+[web]
+LOG_LEVEL=info
+URL=https://example.com/?a=b
+`), 0600)).To(Succeed())
+
+		Expect(appEnv.ParseFile(envFile)).To(Succeed())
+		Expect(appEnv.Apply("api", nil)).To(Equal(map[string]interface{}{
+			"REGION": "eu-west",
+		}))
+		Expect(appEnv.Apply("web", nil)).To(Equal(map[string]interface{}{
+			"REGION":    "eu-west",
+			"LOG_LEVEL": "info",
+			"URL":       "https://example.com/?a=b",
+		}))
+	})
+
+	It("Reports invalid variables and patterns", func() {
+		Expect(appEnv.ParseVar("=value")).To(MatchError("invalid environment variable '=value', expected KEY=VALUE"))
+		Expect(appEnv.ParseVar("NO_VALUE")).To(MatchError("invalid environment variable 'NO_VALUE', expected KEY=VALUE"))
+		Expect(appEnv.AddDropPattern("[")).To(MatchError("invalid environment variable pattern '['"))
+
+		dir, _ := ioutil.TempDir("", "app-env")
+		defer os.RemoveAll(dir)
+
+		envFile := filepath.Join(dir, "env")
+		Expect(ioutil.WriteFile(envFile, []byte("A=1\nB\n"), 0600)).To(Succeed())
+		Expect(appEnv.ParseFile(envFile)).To(MatchError(envFile + ":2: invalid environment variable 'B', expected KEY=VALUE"))
+	})
+})
@@ -0,0 +1,157 @@
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"code.cloudfoundry.org/cli/cf/formatters"
+	"code.cloudfoundry.org/cli/cf/models"
+)
+
+// ScaleValue - An absolute value or a percentage of
+// the corresponding value of the source application
+type ScaleValue struct {
+	Value   int64
+	Percent bool
+}
+
+// Apply - Returns the scaled value for the given source value.
+// Percentages are rounded up so a scaled value is never zero.
+func (v ScaleValue) Apply(srcValue int64) int64 {
+	if !v.Percent {
+		return v.Value
+	}
+	scaled := (srcValue*v.Value + 99) / 100
+	if scaled < 1 {
+		scaled = 1
+	}
+	return scaled
+}
+
+// AppScale - Instance, memory and disk overrides
+// applied to applications copied to the destination
+type AppScale struct {
+	instances scaleOverride
+	memory    scaleOverride
+	disk      scaleOverride
+}
+
+type scaleOverride struct {
+	global *ScaleValue
+	apps   map[string]ScaleValue
+}
+
+// NewAppScale -
+func NewAppScale() *AppScale {
+	return &AppScale{
+		instances: scaleOverride{apps: make(map[string]ScaleValue)},
+		memory:    scaleOverride{apps: make(map[string]ScaleValue)},
+		disk:      scaleOverride{apps: make(map[string]ScaleValue)},
+	}
+}
+
+// IsEmpty -
+func (s *AppScale) IsEmpty() bool {
+	return s.instances.isEmpty() && s.memory.isEmpty() && s.disk.isEmpty()
+}
+
+// ParseInstances - Parses a comma separated list of instance counts
+// of the form 'COUNT', 'PERCENT%', 'APP:COUNT' or 'APP:PERCENT%'
+func (s *AppScale) ParseInstances(arg string) error {
+	return s.instances.parse(arg, "instances", func(value string) (int64, error) {
+		return strconv.ParseInt(value, 10, 64)
+	})
+}
+
+// ParseMemory - Parses a comma separated list of memory limits
+// of the form 'SIZE', 'PERCENT%', 'APP:SIZE' or 'APP:PERCENT%'
+// where SIZE is given with a unit i.e. 256M or 1G
+func (s *AppScale) ParseMemory(arg string) error {
+	return s.memory.parse(arg, "memory", formatters.ToMegabytes)
+}
+
+// ParseDisk - Parses a comma separated list of disk limits
+// of the form 'SIZE', 'PERCENT%', 'APP:SIZE' or 'APP:PERCENT%'
+// where SIZE is given with a unit i.e. 256M or 1G
+func (s *AppScale) ParseDisk(arg string) error {
+	return s.disk.parse(arg, "disk", formatters.ToMegabytes)
+}
+
+// HasInstances -
+func (s *AppScale) HasInstances(appName string) bool {
+	return s.instances.has(appName)
+}
+
+// HasMemory -
+func (s *AppScale) HasMemory(appName string) bool {
+	return s.memory.has(appName)
+}
+
+// HasDisk -
+func (s *AppScale) HasDisk(appName string) bool {
+	return s.disk.has(appName)
+}
+
+// Apply - Returns the instance count, memory and disk limits in
+// megabytes of the given source application after scaling
+func (s *AppScale) Apply(app models.ApplicationFields) (instances int, memory int64, disk int64) {
+	instances = int(s.instances.apply(app.Name, int64(app.InstanceCount)))
+	memory = s.memory.apply(app.Name, app.Memory)
+	disk = s.disk.apply(app.Name, app.DiskQuota)
+	return
+}
+
+func (o *scaleOverride) isEmpty() bool {
+	return o.global == nil && len(o.apps) == 0
+}
+
+func (o *scaleOverride) has(appName string) bool {
+	_, exists := o.apps[appName]
+	return exists || o.global != nil
+}
+
+func (o *scaleOverride) apply(appName string, srcValue int64) int64 {
+	if v, exists := o.apps[appName]; exists {
+		return v.Apply(srcValue)
+	}
+	if o.global != nil {
+		return o.global.Apply(srcValue)
+	}
+	return srcValue
+}
+
+func (o *scaleOverride) parse(arg, name string, parseValue func(string) (int64, error)) error {
+
+	for _, item := range strings.Split(arg, ",") {
+
+		var (
+			err      error
+			appName  string
+			scaleVal ScaleValue
+		)
+
+		item = strings.TrimSpace(item)
+		if i := strings.LastIndex(item, ":"); i != -1 {
+			appName = item[:i]
+			item = item[i+1:]
+		}
+
+		if strings.HasSuffix(item, "%") {
+			scaleVal.Percent = true
+			scaleVal.Value, err = strconv.ParseInt(strings.TrimSuffix(item, "%"), 10, 64)
+		} else {
+			scaleVal.Value, err = parseValue(item)
+		}
+		if err != nil || scaleVal.Value < 1 {
+			return fmt.Errorf("invalid %s value '%s'", name, item)
+		}
+
+		if appName == "" {
+			o.global = &scaleVal
+		} else {
+			o.apps[appName] = scaleVal
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package helpers_test
+
+import (
+	"code.cloudfoundry.org/cli/cf/models"
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("App Scale Tests", func() {
+
+	var appScale *AppScale
+
+	BeforeEach(func() {
+		appScale = NewAppScale()
+	})
+
+	It("Scales apps by absolute values and percentages", func() {
+		Expect(appScale.IsEmpty()).To(BeTrue())
+
+		Expect(appScale.ParseInstances("2,web:50%")).To(Succeed())
+		Expect(appScale.ParseMemory("1G,worker:256M")).To(Succeed())
+		Expect(appScale.ParseDisk("web:200%")).To(Succeed())
+		Expect(appScale.IsEmpty()).To(BeFalse())
+
+		web := models.ApplicationFields{Name: "web", InstanceCount: 5, Memory: 512, DiskQuota: 1024}
+		instances, memory, disk := appScale.Apply(web)
+		Expect(instances).To(Equal(3))
+		Expect(memory).To(Equal(int64(1024)))
+		Expect(disk).To(Equal(int64(2048)))
+
+		worker := models.ApplicationFields{Name: "worker", InstanceCount: 4, Memory: 512, DiskQuota: 1024}
+		instances, memory, disk = appScale.Apply(worker)
+		Expect(instances).To(Equal(2))
+		Expect(memory).To(Equal(int64(256)))
+		Expect(disk).To(Equal(int64(1024)))
+
+		Expect(appScale.HasInstances("worker")).To(BeTrue())
+		Expect(appScale.HasDisk("web")).To(BeTrue())
+		Expect(appScale.HasDisk("worker")).To(BeFalse())
+	})
+
+	It("Never scales a value down to zero", func() {
+		Expect(ScaleValue{Value: 10, Percent: true}.Apply(1)).To(Equal(int64(1)))
+		Expect(ScaleValue{Value: 10, Percent: true}.Apply(0)).To(Equal(int64(1)))
+		Expect(ScaleValue{Value: 4}.Apply(1)).To(Equal(int64(4)))
+	})
+
+	It("Reports invalid values", func() {
+		Expect(appScale.ParseInstances("0")).To(MatchError("invalid instances value '0'"))
+		Expect(appScale.ParseInstances("web:-1")).To(MatchError("invalid instances value '-1'"))
+		Expect(appScale.ParseMemory("lots")).To(MatchError("invalid memory value 'lots'"))
+		Expect(appScale.ParseDisk("web:x%")).To(MatchError("invalid disk value 'x%'"))
+	})
+})
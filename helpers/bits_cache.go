@@ -0,0 +1,125 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// BitsCache - An on-disk cache of droplets and application bits addressed
+// by their checksums. When the total size of the cache exceeds its limit
+// the least recently used entries are evicted.
+type BitsCache struct {
+	dir     string
+	maxSize int64
+}
+
+var cacheKeyPattern = regexp.MustCompile(`^[a-z0-9]+-[a-f0-9]+$`)
+
+// NewBitsCache - Creates a cache in the given directory which
+// is limited to the given number of bytes
+func NewBitsCache(dir string, maxSize int64) (*BitsCache, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &BitsCache{dir: dir, maxSize: maxSize}, nil
+}
+
+// CacheKey - Returns the cache key of bits with the given checksum
+func CacheKey(checksumType, checksum string) string {
+	return checksumType + "-" + checksum
+}
+
+// Get - Opens the cached bits with the given key and marks them as
+// recently used. The file must be closed by the caller.
+func (c *BitsCache) Get(key string) (file *os.File, size int64, ok bool) {
+
+	if !cacheKeyPattern.MatchString(key) {
+		return nil, 0, false
+	}
+	path := filepath.Join(c.dir, key)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, 0, false
+	}
+	if file, err = os.Open(path); err != nil {
+		return nil, 0, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return file, info.Size(), true
+}
+
+// Put - Adds the bits written by the given function to the cache. The
+// entry is discarded if the function returns an error so that partial
+// or corrupt bits are never cached.
+func (c *BitsCache) Put(key string, write func(w io.Writer) error) (err error) {
+
+	var file *os.File
+
+	if !cacheKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid cache key '%s'", key)
+	}
+	if file, err = ioutil.TempFile(c.dir, ".partial-"); err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	if err = os.Rename(file.Name(), filepath.Join(c.dir, key)); err != nil {
+		return
+	}
+	return c.evict(key)
+}
+
+// evict - Removes the least recently used entries until the cache fits
+// within its size limit. The given entry is never evicted.
+func (c *BitsCache) evict(keep string) error {
+
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	entries := []os.FileInfo{}
+	size := int64(0)
+	for _, f := range files {
+		if f.Mode().IsRegular() && cacheKeyPattern.MatchString(f.Name()) {
+			entries = append(entries, f)
+			size += f.Size()
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+
+	for _, f := range entries {
+		if size <= c.maxSize {
+			break
+		}
+		if f.Name() == keep {
+			continue
+		}
+		if err = os.Remove(filepath.Join(c.dir, f.Name())); err != nil {
+			return err
+		}
+		size -= f.Size()
+	}
+	return nil
+}
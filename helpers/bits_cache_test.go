@@ -0,0 +1,91 @@
+package helpers_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bits Cache Tests", func() {
+
+	var (
+		dir   string
+		cache *BitsCache
+	)
+
+	put := func(key, content string) {
+		err := cache.Put(key, func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+
+		dir, err = ioutil.TempDir("", "bits-cache")
+		Expect(err).NotTo(HaveOccurred())
+		cache, err = NewBitsCache(dir, 10)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("Returns cached bits", func() {
+		put(CacheKey("sha256", "aa"), "12345")
+
+		file, size, ok := cache.Get(CacheKey("sha256", "aa"))
+		Expect(ok).To(BeTrue())
+		defer file.Close()
+
+		data, err := ioutil.ReadAll(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("12345"))
+		Expect(size).To(Equal(int64(5)))
+	})
+
+	It("Does not cache bits that failed to be written", func() {
+		err := cache.Put(CacheKey("sha256", "bb"), func(w io.Writer) error {
+			io.WriteString(w, "123")
+			return io.ErrUnexpectedEOF
+		})
+		Expect(err).To(HaveOccurred())
+
+		_, _, ok := cache.Get(CacheKey("sha256", "bb"))
+		Expect(ok).To(BeFalse())
+
+		files, _ := ioutil.ReadDir(dir)
+		Expect(files).To(BeEmpty())
+	})
+
+	It("Evicts the least recently used bits", func() {
+		put(CacheKey("sha256", "aa"), "1234")
+		put(CacheKey("sha256", "bb"), "1234")
+
+		old := time.Now().Add(-time.Hour)
+		os.Chtimes(filepath.Join(dir, CacheKey("sha256", "bb")), old, old)
+		file, _, ok := cache.Get(CacheKey("sha256", "aa"))
+		Expect(ok).To(BeTrue())
+		file.Close()
+
+		put(CacheKey("sha256", "cc"), strings.Repeat("1", 4))
+
+		_, _, ok = cache.Get(CacheKey("sha256", "bb"))
+		Expect(ok).To(BeFalse())
+		for _, key := range []string{"aa", "cc"} {
+			file, _, ok = cache.Get(CacheKey("sha256", key))
+			Expect(ok).To(BeTrue())
+			file.Close()
+		}
+	})
+})
@@ -0,0 +1,175 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// CCClient - A minimal Cloud Controller client used for API calls
+// that are not provided by the CLI sessions, such as the v3 API
+type CCClient struct {
+	endpoint    string
+	accessToken string
+
+	httpClient *http.Client
+	retry      *RetryPolicy
+	refresher  *TokenRefresher
+}
+
+// ccConfig - The fields of a CLI config file used by the client
+type ccConfig struct {
+	Target      string
+	AccessToken string
+	SSLDisabled bool
+}
+
+// CCError - An error response from the Cloud Controller
+type CCError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *CCError) Error() string {
+	return fmt.Sprintf("Cloud Controller request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// NewCCClientFromFilepath - Creates a client for the target
+// and access token saved in the given CLI config file
+func NewCCClientFromFilepath(configPath string) (*CCClient, error) {
+
+	var config ccConfig
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if config.Target == "" {
+		return nil, fmt.Errorf("no API endpoint is set in '%s'", configPath)
+	}
+
+	return &CCClient{
+		endpoint:    strings.TrimSuffix(config.Target, "/"),
+		accessToken: config.AccessToken,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SSLDisabled},
+			},
+		},
+	}, nil
+}
+
+// SetRetryPolicy - Sets the policy used to retry idempotent requests
+func (c *CCClient) SetRetryPolicy(retry *RetryPolicy) {
+	c.retry = retry
+}
+
+// SetTokenRefresher - Sets the refresher used to keep the access
+// token of the client valid during long running copies
+func (c *CCClient) SetTokenRefresher(refresher *TokenRefresher) {
+	c.refresher = refresher
+}
+
+// Do - Sends a request with the given body marshalled as JSON to the
+// given API path and unmarshals the JSON response into the result.
+// Requests other than POSTs are retried if they fail with a transient
+// error as they can be safely repeated.
+func (c *CCClient) Do(method, path string, body interface{}, result interface{}) error {
+
+	var (
+		data   []byte
+		header = http.Header{}
+	)
+
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	header.Set("Accept", "application/json")
+
+	do := func() error {
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(data)
+		}
+
+		response, err := c.Request(method, path, reader, -1, header)
+		if ccErr, ok := err.(*CCError); ok && ccErr.StatusCode == http.StatusUnauthorized && c.refresher != nil {
+			// The token may have been revoked before it expired
+			if _, err = c.refresher.Refresh(); err != nil {
+				return err
+			}
+			if body != nil {
+				reader = bytes.NewReader(data)
+			}
+			response, err = c.Request(method, path, reader, -1, header)
+		}
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+
+		responseData, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return err
+		}
+		if result != nil && len(responseData) > 0 {
+			return json.Unmarshal(responseData, result)
+		}
+		return nil
+	}
+
+	if method == "POST" {
+		return do()
+	}
+	return c.retry.Do(method+" "+path, do)
+}
+
+// Request - Sends a request with the given body to the given API path
+// and returns the response which must be closed by the caller. If the
+// content length is negative the body is sent using chunked encoding.
+// Redirects are followed without sending the access token to other
+// hosts. Responses with an error status are returned as a CCError.
+func (c *CCClient) Request(method, path string, body io.Reader, contentLength int64, header http.Header) (*http.Response, error) {
+
+	request, err := http.NewRequest(method, c.endpoint+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		request.Header[k] = v
+	}
+	accessToken := c.accessToken
+	if c.refresher != nil {
+		if accessToken, _, err = c.refresher.AccessToken(); err != nil {
+			return nil, err
+		}
+	}
+	request.Header.Set("Authorization", accessToken)
+	if body != nil && contentLength >= 0 {
+		request.ContentLength = contentLength
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 400 {
+		defer response.Body.Close()
+		data, _ := ioutil.ReadAll(response.Body)
+		return nil, &CCError{StatusCode: response.StatusCode, Body: string(data)}
+	}
+	return response, nil
+}
@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CopyPlan - A plan of copies between many spaces that are run as one
+// batch. At most the given number of copies are run at the same time.
+type CopyPlan struct {
+	Parallel int           `yaml:"parallel"`
+	Copies   []PlannedCopy `yaml:"copies"`
+}
+
+// PlannedCopy - A copy of a plan given by its source and
+// destination and the options used for the copy
+type PlannedCopy struct {
+	Name        string                 `yaml:"name"`
+	Source      PlanLocation           `yaml:"source"`
+	Destination PlanLocation           `yaml:"destination"`
+	Options     map[string]interface{} `yaml:"options"`
+}
+
+// PlanLocation - The target, org and space of a planned copy. Those
+// not given default as they do for the arguments of the copy command.
+type PlanLocation struct {
+	Target string `yaml:"target"`
+	Org    string `yaml:"org"`
+	Space  string `yaml:"space"`
+}
+
+// String - Describes the location
+func (l PlanLocation) String() string {
+	parts := []string{}
+	for _, p := range []string{l.Target, l.Org, l.Space} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// ReadCopyPlan - Reads and validates the copy plan in the given file
+func ReadCopyPlan(path string) (*CopyPlan, error) {
+
+	var plan CopyPlan
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("invalid copy plan '%s': %s", path, err.Error())
+	}
+
+	if len(plan.Copies) == 0 {
+		return nil, fmt.Errorf("the copy plan '%s' has no copies", path)
+	}
+	if plan.Parallel < 0 {
+		return nil, fmt.Errorf("invalid parallel copies %d in copy plan '%s'", plan.Parallel, path)
+	}
+	if plan.Parallel == 0 {
+		plan.Parallel = 1
+	}
+	for i, c := range plan.Copies {
+		if c.Destination.Space == "" {
+			return nil, fmt.Errorf("copy %d of plan '%s' has no destination space", i+1, path)
+		}
+		if c.Source.Org != "" && c.Source.Space == "" {
+			return nil, fmt.Errorf("copy %d of plan '%s' must have a source space with the source org", i+1, path)
+		}
+		if c.Name == "" {
+			plan.Copies[i].Name = c.Destination.String()
+		}
+	}
+	return &plan, nil
+}
@@ -0,0 +1,164 @@
+package helpers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// CopyConfigFile - The name of the copy configuration file
+// which is looked up in the current directory by default
+const CopyConfigFile = ".cf-copy.yml"
+
+// copyConfig - A copy configuration file with named profiles of
+// option values keyed by the long name of the option, the destinations
+// that may only be copied to when explicitly allowed and the chains of
+// stages apps are promoted through
+type copyConfig struct {
+	Profiles  map[string]map[string]interface{} `yaml:"profiles"`
+	Protected []ProtectedDestination            `yaml:"protected"`
+	Chains    map[string][]PromotionStage       `yaml:"chains"`
+}
+
+// PromotionStage - A stage of a promotion chain given by the target, org
+// and space of the stage and the options used when copying to it
+type PromotionStage struct {
+	Name    string                 `yaml:"name"`
+	Target  string                 `yaml:"target"`
+	Org     string                 `yaml:"org"`
+	Space   string                 `yaml:"space"`
+	Options map[string]interface{} `yaml:"options"`
+}
+
+// ProtectedDestination - A protected destination given by the target, org
+// and space it applies to. Each may be a pattern such as "prod-*" and one
+// that is not set matches any value.
+type ProtectedDestination struct {
+	Target string `yaml:"target"`
+	Org    string `yaml:"org"`
+	Space  string `yaml:"space"`
+}
+
+// Matches - Returns whether the given destination is protected
+func (p ProtectedDestination) Matches(target, org, space string) bool {
+	return matchesPattern(p.Target, target) &&
+		matchesPattern(p.Org, org) &&
+		matchesPattern(p.Space, space)
+}
+
+// String - Describes the protected destination
+func (p ProtectedDestination) String() string {
+	parts := []string{}
+	if p.Target != "" {
+		parts = append(parts, "target "+p.Target)
+	}
+	if p.Org != "" {
+		parts = append(parts, "org "+p.Org)
+	}
+	if p.Space != "" {
+		parts = append(parts, "space "+p.Space)
+	}
+	return strings.Join(parts, " / ")
+}
+
+func matchesPattern(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// FindCopyConfig - Returns the path of the copy configuration file
+// in the current directory or an empty path if there is none
+func FindCopyConfig() string {
+	if info, err := os.Stat(CopyConfigFile); err == nil && info.Mode().IsRegular() {
+		return CopyConfigFile
+	}
+	return ""
+}
+
+// ReadCopyProfile - Reads the option values of the named
+// profile from the given copy configuration file
+func ReadCopyProfile(path, name string) (map[string]interface{}, error) {
+
+	config, err := readCopyConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found in '%s'", name, path)
+	}
+	return profile, nil
+}
+
+// ReadProtectedDestinations - Reads the protected destinations
+// from the given copy configuration file
+func ReadProtectedDestinations(configPath string) ([]ProtectedDestination, error) {
+
+	config, err := readCopyConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, p := range config.Protected {
+		if p.Target == "" && p.Org == "" && p.Space == "" {
+			return nil, fmt.Errorf("protected destination %d in '%s' does not set a target, org or space", i+1, configPath)
+		}
+		for _, pattern := range []string{p.Target, p.Org, p.Space} {
+			if _, err = path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s' of protected destination %d in '%s'", pattern, i+1, configPath)
+			}
+		}
+	}
+	return config.Protected, nil
+}
+
+// ReadPromotionChain - Reads the stages of the named promotion
+// chain from the given copy configuration file
+func ReadPromotionChain(path, name string) ([]PromotionStage, error) {
+
+	config, err := readCopyConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stages, ok := config.Chains[name]
+	if !ok {
+		return nil, fmt.Errorf("chain '%s' not found in '%s'", name, path)
+	}
+	if len(stages) < 2 {
+		return nil, fmt.Errorf("chain '%s' in '%s' must have at least two stages", name, path)
+	}
+	names := make(map[string]bool)
+	for i, s := range stages {
+		if s.Name == "" || s.Space == "" {
+			return nil, fmt.Errorf("stage %d of chain '%s' in '%s' must have a name and a space", i+1, name, path)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("stage '%s' appears more than once in chain '%s' in '%s'", s.Name, name, path)
+		}
+		names[s.Name] = true
+	}
+	return stages, nil
+}
+
+func readCopyConfig(path string) (*copyConfig, error) {
+
+	var config copyConfig
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid copy configuration '%s': %s", path, err.Error())
+	}
+	return &config, nil
+}
@@ -0,0 +1,97 @@
+package helpers
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mevansam/cf-cli-api/cfapi"
+)
+
+// DownloadCache - The application bits and droplets downloaded by the
+// sessions created with it. They are kept in the given directory for
+// as long as the copies made from them run.
+type DownloadCache struct {
+	dir        string
+	downloaded map[string]bool
+	mutex      sync.Mutex
+}
+
+// NewDownloadCache - Creates a cache of the downloads kept in the
+// given directory
+func NewDownloadCache(dir string) *DownloadCache {
+	return &DownloadCache{dir: dir, downloaded: make(map[string]bool)}
+}
+
+func (c *DownloadCache) path(appGUID string, asDroplet bool) string {
+	if asDroplet {
+		return filepath.Join(c.dir, appGUID+".droplet")
+	}
+	return filepath.Join(c.dir, appGUID+".bits")
+}
+
+func (c *DownloadCache) isDownloaded(path string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.downloaded[path]
+}
+
+// NewDownloadCacheSession - Wraps the given session so that the content
+// of an application is only downloaded once by all the sessions that
+// share the given cache
+func NewDownloadCacheSession(session cfapi.CfSession, cache *DownloadCache) cfapi.CfSession {
+	return &downloadCacheSession{CfSession: session, cache: cache}
+}
+
+// downloadCacheSession - The applications manager downloads the source
+// applications for each destination it copies them to so the content
+// downloaded for the first destination is copied for the others
+type downloadCacheSession struct {
+	cfapi.CfSession
+	cache *DownloadCache
+}
+
+// DownloadAppContent - Copies the content of the application from the
+// cache or downloads it and adds it to the cache
+func (s *downloadCacheSession) DownloadAppContent(appGUID string, outputFile *os.File, asDroplet bool) (err error) {
+
+	var file *os.File
+
+	path := s.cache.path(appGUID, asDroplet)
+	if s.cache.isDownloaded(path) {
+		if file, err = os.Open(path); err != nil {
+			return
+		}
+		defer file.Close()
+
+		if _, err = outputFile.Seek(0, io.SeekStart); err != nil {
+			return
+		}
+		if err = outputFile.Truncate(0); err != nil {
+			return
+		}
+		_, err = io.Copy(outputFile, file)
+		return
+	}
+
+	if err = s.CfSession.DownloadAppContent(appGUID, outputFile, asDroplet); err != nil {
+		return
+	}
+	if file, err = os.Create(path); err != nil {
+		return
+	}
+	defer file.Close()
+
+	if _, err = outputFile.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	if _, err = io.Copy(file, outputFile); err != nil {
+		os.Remove(path)
+		return
+	}
+	s.cache.mutex.Lock()
+	s.cache.downloaded[path] = true
+	s.cache.mutex.Unlock()
+	return
+}
@@ -0,0 +1,236 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"time"
+)
+
+// DropletChecksums - Checksums of the droplet bits
+// computed while they are being transferred
+type DropletChecksums struct {
+	SHA1   string
+	SHA256 string
+	Size   int64
+}
+
+// Matches - Returns whether the given checksum of the
+// given type matches the checksum of the transferred bits
+func (c DropletChecksums) Matches(checksumType, value string) bool {
+	switch checksumType {
+	case "sha1":
+		return c.SHA1 == value
+	case "sha256":
+		return c.SHA256 == value
+	}
+	return false
+}
+
+// checksumReader - Computes checksums of the bits read through it
+type checksumReader struct {
+	reader io.Reader
+	sha1   hash.Hash
+	sha256 hash.Hash
+	size   int64
+}
+
+func newChecksumReader(reader io.Reader) *checksumReader {
+	return &checksumReader{reader: reader, sha1: sha1.New(), sha256: sha256.New()}
+}
+
+func (r *checksumReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.sha1.Write(p[:n])
+	r.sha256.Write(p[:n])
+	r.size += int64(n)
+	return
+}
+
+func (r *checksumReader) checksums() DropletChecksums {
+	return DropletChecksums{
+		SHA1:   hex.EncodeToString(r.sha1.Sum(nil)),
+		SHA256: hex.EncodeToString(r.sha256.Sum(nil)),
+		Size:   r.size,
+	}
+}
+
+// StreamDroplet - Transfers the droplet of the source application to the
+// destination application by piping the download from the source Cloud
+// Controller directly into the upload to the destination. Only the bits
+// in flight are held in memory. If the destination requires the length
+// of the upload to be known the droplet is staged in a temporary file.
+func StreamDroplet(
+	src *CCClient, srcAppGUID string,
+	dest *CCClient, destAppGUID string,
+	timeout time.Duration, progress *Progress) (checksums DropletChecksums, err error) {
+
+	var download *http.Response
+
+	if download, err = src.Request("GET", fmt.Sprintf("/v2/apps/%s/droplet/download", srcAppGUID), nil, -1, nil); err != nil {
+		return
+	}
+	defer download.Body.Close()
+
+	body, done := progress.Reader("streaming droplet", download.Body, download.ContentLength)
+	reader := newChecksumReader(body)
+	pipeReader, pipeWriter := io.Pipe()
+	multipartWriter := multipart.NewWriter(pipeWriter)
+
+	go func() {
+		part, err := multipartWriter.CreateFormFile("droplet", "droplet.tgz")
+		if err == nil {
+			if _, err = io.Copy(part, reader); err == nil {
+				err = multipartWriter.Close()
+			}
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+
+	err = uploadDroplet(dest, destAppGUID, pipeReader, -1, multipartWriter.FormDataContentType(), timeout)
+	pipeReader.Close()
+	done()
+
+	if ccErr, ok := err.(*CCError); ok && ccErr.StatusCode == http.StatusLengthRequired {
+		download.Body.Close()
+		return transferDropletViaFile(src, srcAppGUID, dest, destAppGUID, timeout, progress)
+	}
+	checksums = reader.checksums()
+	return
+}
+
+// transferDropletViaFile - Downloads the droplet to a temporary file so
+// that it can be uploaded with a known length and removes the file once
+// the upload is done
+func transferDropletViaFile(
+	src *CCClient, srcAppGUID string,
+	dest *CCClient, destAppGUID string,
+	timeout time.Duration, progress *Progress) (checksums DropletChecksums, err error) {
+
+	var file *os.File
+
+	if file, err = ioutil.TempFile("", "droplet"); err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if checksums, err = DownloadDroplet(src, srcAppGUID, file, progress); err != nil {
+		return
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	err = UploadDropletFile(dest, destAppGUID, file, checksums.Size, timeout, progress)
+	return
+}
+
+// DownloadDroplet - Writes the droplet of the given application to
+// the given writer and returns the checksums of the downloaded bits
+func DownloadDroplet(src *CCClient, appGUID string, w io.Writer, progress *Progress) (checksums DropletChecksums, err error) {
+
+	var download *http.Response
+
+	if download, err = src.Request("GET", fmt.Sprintf("/v2/apps/%s/droplet/download", appGUID), nil, -1, nil); err != nil {
+		return
+	}
+	defer download.Body.Close()
+
+	body, done := progress.Reader("downloading droplet", download.Body, download.ContentLength)
+	defer done()
+
+	reader := newChecksumReader(body)
+	if _, err = io.Copy(w, reader); err != nil {
+		return
+	}
+	checksums = reader.checksums()
+	return
+}
+
+// UploadDropletFile - Uploads a droplet of the given size to the given
+// application. The multipart framing is sent around the droplet bits so
+// that the length of the upload is known without copying the droplet.
+func UploadDropletFile(
+	dest *CCClient, appGUID string,
+	droplet io.Reader, size int64,
+	timeout time.Duration, progress *Progress) (err error) {
+
+	var framing bytes.Buffer
+
+	multipartWriter := multipart.NewWriter(&framing)
+	if _, err = multipartWriter.CreateFormFile("droplet", "droplet.tgz"); err != nil {
+		return
+	}
+	header := append([]byte{}, framing.Bytes()...)
+
+	// Closing the writer only writes the closing boundary
+	framing.Reset()
+	if err = multipartWriter.Close(); err != nil {
+		return
+	}
+	trailer := framing.Bytes()
+
+	droplet, done := progress.Reader("uploading droplet", droplet, size)
+	defer done()
+
+	body := io.MultiReader(bytes.NewReader(header), droplet, bytes.NewReader(trailer))
+	length := int64(len(header)) + size + int64(len(trailer))
+	return uploadDroplet(dest, appGUID, body, length, multipartWriter.FormDataContentType(), timeout)
+}
+
+// uploadDroplet - Uploads a multipart droplet body and waits for the
+// asynchronous upload job to complete
+func uploadDroplet(dest *CCClient, appGUID string, body io.Reader, length int64, contentType string, timeout time.Duration) (err error) {
+
+	var (
+		response *http.Response
+		job      struct {
+			Metadata struct {
+				GUID string `json:"guid"`
+			} `json:"metadata"`
+			Entity struct {
+				Status string `json:"status"`
+				Error  string `json:"error"`
+			} `json:"entity"`
+		}
+	)
+
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+
+	if response, err = dest.Request("PUT", fmt.Sprintf("/v2/apps/%s/droplet/upload?async=true", appGUID), body, length, header); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusAccepted {
+		return
+	}
+	if err = json.NewDecoder(response.Body).Decode(&job); err != nil {
+		return
+	}
+
+	deadline := time.Now().Add(timeout)
+	for job.Entity.Status != "finished" {
+		if job.Entity.Status == "failed" {
+			return fmt.Errorf("droplet upload job failed: %s", job.Entity.Error)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the droplet upload to complete")
+		}
+		time.Sleep(2 * time.Second)
+		if err = dest.Do("GET", "/v2/jobs/"+job.Metadata.GUID, nil, &job); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,170 @@
+package helpers
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/formatters"
+	"code.cloudfoundry.org/cli/cf/terminal"
+)
+
+const (
+	progressBarWidth    = 20
+	progressTTYInterval = 250 * time.Millisecond
+	progressLogInterval = 10 * time.Second
+)
+
+// Progress - Reports the progress of transfers through a UI. On a
+// terminal a progress bar with the bytes transferred, the rate and the
+// time remaining is redrawn in place. Otherwise a plain line is logged
+// periodically. A nil progress reports nothing.
+type Progress struct {
+	ui       terminal.UI
+	tty      bool
+	interval time.Duration
+	label    string
+}
+
+// NewProgress - Creates a progress reporter writing to the given UI.
+// The progress is redrawn in place only if the UI writes to a terminal
+// that no other copy writes its progress to at the same time.
+func NewProgress(ui terminal.UI, tty bool) *Progress {
+
+	interval := progressLogInterval
+	if tty {
+		interval = progressTTYInterval
+	}
+	return &Progress{ui: ui, tty: tty, interval: interval}
+}
+
+// IsTerminal - Returns whether the given file is a character device
+// such as a terminal rather than a pipe or a regular file
+var IsTerminal = func(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// For - Returns a reporter that labels transfers with the given name
+func (p *Progress) For(name string) *Progress {
+	if p == nil {
+		return nil
+	}
+	labelled := *p
+	labelled.label = name
+	return &labelled
+}
+
+// Reader - Returns a reader that reports the progress of reading the
+// given number of bytes from the given reader. The returned function
+// must be called once the transfer is done. If the total is not known
+// it should be given as a negative value.
+func (p *Progress) Reader(action string, r io.Reader, total int64) (io.Reader, func()) {
+	if p == nil {
+		return r, func() {}
+	}
+
+	pr := &progressReader{reader: r}
+	return pr, p.Watch(action, total, func() int64 {
+		return atomic.LoadInt64(&pr.count)
+	})
+}
+
+// Watch - Reports the progress of a transfer by polling the number of
+// bytes transferred so far using the given function. The returned
+// function must be called once the transfer is done.
+func (p *Progress) Watch(action string, total int64, transferred func() int64) func() {
+	if p == nil {
+		return func() {}
+	}
+
+	done := make(chan bool)
+	stopped := make(chan bool)
+	start := time.Now()
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.render(action, transferred(), total, time.Since(start), false)
+			case <-done:
+				p.render(action, transferred(), total, time.Since(start), true)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (p *Progress) render(action string, count, total int64, elapsed time.Duration, final bool) {
+
+	rate := int64(0)
+	if elapsed > 0 {
+		rate = int64(float64(count) / elapsed.Seconds())
+	}
+
+	label := action
+	if p.label != "" {
+		label = p.label + ": " + action
+	}
+
+	status := formatters.ByteSize(count)
+	if total > 0 {
+		status += " of " + formatters.ByteSize(total)
+	}
+	status += fmt.Sprintf(" at %s/s", formatters.ByteSize(rate))
+	if total > 0 && rate > 0 && count < total && !final {
+		eta := time.Duration((total-count)/rate) * time.Second
+		status += " ETA " + eta.String()
+	}
+	if final {
+		status += " in " + (elapsed - elapsed%time.Second).String()
+	}
+
+	if !p.tty {
+		if total > 0 {
+			p.ui.Say("%s %d%% %s", label, count*100/total, status)
+		} else {
+			p.ui.Say("%s %s", label, status)
+		}
+		return
+	}
+
+	bar := ""
+	if total > 0 {
+		filled := int(count * progressBarWidth / total)
+		if filled > progressBarWidth {
+			filled = progressBarWidth
+		}
+		bar = " [" + strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled) + "]"
+	}
+
+	// Redraw the line in place and clear what remains of the previous one
+	p.ui.PrintCapturingNoOutput("\r%s%s %s\033[K", label, bar, status)
+	if final {
+		p.ui.Say("")
+	}
+}
+
+// progressReader - Counts the bytes read through it
+type progressReader struct {
+	count  int64
+	reader io.Reader
+}
+
+func (r *progressReader) Read(b []byte) (n int, err error) {
+	n, err = r.reader.Read(b)
+	atomic.AddInt64(&r.count, int64(n))
+	return
+}
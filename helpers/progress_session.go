@@ -0,0 +1,66 @@
+package helpers
+
+import (
+	"io"
+	"os"
+
+	"github.com/mevansam/cf-cli-api/cfapi"
+)
+
+// NewProgressSession - Wraps the given session so that the progress of
+// the application bits and droplets it transfers is reported. The given
+// function, if any, is called with the GUID of the application and the
+// action when a transfer starts.
+func NewProgressSession(session cfapi.CfSession, progress *Progress, onTransfer func(appGUID, action string)) cfapi.CfSession {
+	return &progressSession{CfSession: session, progress: progress, onTransfer: onTransfer}
+}
+
+// progressSession - Reports transfers made by a session by polling the
+// files they are written to or read from
+type progressSession struct {
+	cfapi.CfSession
+	progress   *Progress
+	onTransfer func(appGUID, action string)
+}
+
+func (s *progressSession) started(appGUID, action string) {
+	if s.onTransfer != nil {
+		s.onTransfer(appGUID, action)
+	}
+}
+
+func (s *progressSession) DownloadAppContent(appGUID string, outputFile *os.File, asDroplet bool) error {
+
+	action := "downloading app bits"
+	if asDroplet {
+		action = "downloading droplet"
+	}
+	s.started(appGUID, action)
+	done := s.progress.Watch(action, -1, func() int64 {
+		if info, err := outputFile.Stat(); err == nil {
+			return info.Size()
+		}
+		return 0
+	})
+	defer done()
+
+	return s.CfSession.DownloadAppContent(appGUID, outputFile, asDroplet)
+}
+
+func (s *progressSession) UploadDroplet(appGUID string, contentType string, dropletUploadRequest *os.File) error {
+
+	total := int64(-1)
+	if info, err := dropletUploadRequest.Stat(); err == nil {
+		total = info.Size()
+	}
+	s.started(appGUID, "uploading droplet")
+	done := s.progress.Watch("uploading droplet", total, func() int64 {
+		if offset, err := dropletUploadRequest.Seek(0, io.SeekCurrent); err == nil {
+			return offset
+		}
+		return 0
+	})
+	defer done()
+
+	return s.CfSession.UploadDroplet(appGUID, contentType, dropletUploadRequest)
+}
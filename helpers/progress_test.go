@@ -0,0 +1,41 @@
+package helpers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"code.cloudfoundry.org/cli/cf/terminal"
+	"code.cloudfoundry.org/cli/cf/trace"
+	io_helpers "code.cloudfoundry.org/cli/util/testhelpers/io"
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Progress Tests", func() {
+
+	It("Logs plain progress lines through the UI when the output is not a terminal", func() {
+		output := io_helpers.CaptureOutput(func() {
+			ui := terminal.NewUI(os.Stdin, os.Stdout, terminal.NewTeePrinter(os.Stdout), trace.NewLogger(os.Stdout, false, "", ""))
+
+			progress := NewProgress(ui, false).For("fake_app")
+			reader, done := progress.Reader("downloading droplet", strings.NewReader(strings.Repeat("x", 2048)), 2048)
+			_, err := ioutil.ReadAll(reader)
+			Expect(err).NotTo(HaveOccurred())
+			done()
+		})
+
+		Expect(output[0]).To(HavePrefix("fake_app: downloading droplet 100% 2K of 2K"))
+		Expect(strings.Join(output, "\n")).NotTo(ContainSubstring("\r"))
+	})
+
+	It("Reports nothing if there is no progress reporter", func() {
+		var progress *Progress
+
+		reader, done := progress.For("fake_app").Reader("downloading droplet", strings.NewReader("x"), 1)
+		_, err := ioutil.ReadAll(reader)
+		Expect(err).NotTo(HaveOccurred())
+		done()
+	})
+})
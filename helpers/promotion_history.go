@@ -0,0 +1,52 @@
+package helpers
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// PromotionHistoryFile - The name of the file promotions are recorded
+// in. It is kept next to the copy configuration file.
+const PromotionHistoryFile = ".cf-copy-history"
+
+// PromotionRecord - A promotion of apps from one stage of a
+// chain to the next with the versions of the apps promoted
+type PromotionRecord struct {
+	Time  time.Time     `json:"time"`
+	User  string        `json:"user"`
+	Chain string        `json:"chain"`
+	From  string        `json:"from"`
+	To    string        `json:"to"`
+	Apps  []PromotedApp `json:"apps"`
+}
+
+// PromotedApp - The version of a promoted app given by
+// the droplet of the source app that was promoted
+type PromotedApp struct {
+	Name            string `json:"name"`
+	DropletGUID     string `json:"droplet_guid,omitempty"`
+	DropletChecksum string `json:"droplet_checksum,omitempty"`
+}
+
+// AppendPromotionRecord - Appends a record to the given history file
+// which has one JSON record per line
+func AppendPromotionRecord(path string, record PromotionRecord) (err error) {
+
+	var (
+		file *os.File
+		data []byte
+	)
+
+	if data, err = json.Marshal(record); err != nil {
+		return
+	}
+	if file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644); err != nil {
+		return
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return
+	}
+	return file.Close()
+}
@@ -0,0 +1,82 @@
+package helpers
+
+import (
+	"strings"
+	"time"
+)
+
+// ProvenancePrefix - The prefix of the annotations that record
+// the source of the apps and services created by a copy
+const ProvenancePrefix = "cf-copy/"
+
+// Provenance - The source of a copied app or service and when it was
+// copied. Only one of the source app and source service is set.
+type Provenance struct {
+	SourceTarget  string
+	SourceOrg     string
+	SourceSpace   string
+	SourceApp     string
+	SourceService string
+
+	// SourceGUID - The GUID of the source app or service
+	SourceGUID string
+	// SourceDroplet - The GUID of the droplet of the source app
+	// when it was copied. It is not set for services.
+	SourceDroplet string
+
+	CopiedAt time.Time
+}
+
+// Annotations - Returns the annotations recording the provenance
+func (p Provenance) Annotations() map[string]string {
+
+	annotations := make(map[string]string)
+	for name, value := range map[string]string{
+		"source-target":  p.SourceTarget,
+		"source-org":     p.SourceOrg,
+		"source-space":   p.SourceSpace,
+		"source-app":     p.SourceApp,
+		"source-service": p.SourceService,
+		"source-guid":    p.SourceGUID,
+		"source-droplet": p.SourceDroplet,
+	} {
+		if value != "" {
+			annotations[ProvenancePrefix+name] = value
+		}
+	}
+	annotations[ProvenancePrefix+"copied-at"] = p.CopiedAt.UTC().Format(time.RFC3339)
+	return annotations
+}
+
+// ParseProvenance - Returns the provenance recorded in the given
+// annotations or false if they do not record the source of a copy
+func ParseProvenance(annotations map[string]string) (p Provenance, ok bool) {
+
+	value := func(name string) string {
+		return annotations[ProvenancePrefix+name]
+	}
+
+	p = Provenance{
+		SourceTarget:  value("source-target"),
+		SourceOrg:     value("source-org"),
+		SourceSpace:   value("source-space"),
+		SourceApp:     value("source-app"),
+		SourceService: value("source-service"),
+		SourceGUID:    value("source-guid"),
+		SourceDroplet: value("source-droplet"),
+	}
+	if p.SourceTarget == "" || p.SourceOrg == "" || p.SourceSpace == "" || (p.SourceApp == "") == (p.SourceService == "") {
+		return Provenance{}, false
+	}
+	p.CopiedAt, _ = time.Parse(time.RFC3339, value("copied-at"))
+	return p, true
+}
+
+// Source - Describes the source of the copy
+func (p Provenance) Source() string {
+	name := p.SourceApp
+	if name == "" {
+		name = p.SourceService
+	}
+	return strings.Join([]string{p.SourceTarget, p.SourceOrg, p.SourceSpace, name}, "/")
+}
@@ -0,0 +1,54 @@
+package helpers_test
+
+import (
+	"time"
+
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Provenance Tests", func() {
+
+	It("Records the source of a copied app in annotations", func() {
+		p := Provenance{
+			SourceTarget:  "dev",
+			SourceOrg:     "acme",
+			SourceSpace:   "reference",
+			SourceApp:     "web",
+			SourceGUID:    "app-guid",
+			SourceDroplet: "droplet-guid",
+			CopiedAt:      time.Date(2018, 3, 1, 12, 30, 0, 0, time.UTC),
+		}
+
+		annotations := p.Annotations()
+		Expect(annotations).To(Equal(map[string]string{
+			"cf-copy/source-target":  "dev",
+			"cf-copy/source-org":     "acme",
+			"cf-copy/source-space":   "reference",
+			"cf-copy/source-app":     "web",
+			"cf-copy/source-guid":    "app-guid",
+			"cf-copy/source-droplet": "droplet-guid",
+			"cf-copy/copied-at":      "2018-03-01T12:30:00Z",
+		}))
+
+		parsed, ok := ParseProvenance(annotations)
+		Expect(ok).To(BeTrue())
+		Expect(parsed).To(Equal(p))
+		Expect(parsed.Source()).To(Equal("dev/acme/reference/web"))
+	})
+
+	It("Ignores annotations that do not record a copy", func() {
+		_, ok := ParseProvenance(map[string]string{"owner": "team-a"})
+		Expect(ok).To(BeFalse())
+
+		_, ok = ParseProvenance(map[string]string{
+			"cf-copy/source-target":  "dev",
+			"cf-copy/source-org":     "acme",
+			"cf-copy/source-space":   "reference",
+			"cf-copy/source-app":     "web",
+			"cf-copy/source-service": "db",
+		})
+		Expect(ok).To(BeFalse())
+	})
+})
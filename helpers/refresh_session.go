@@ -0,0 +1,123 @@
+package helpers
+
+import (
+	"os"
+	"sync"
+
+	"code.cloudfoundry.org/cli/cf/api"
+	"code.cloudfoundry.org/cli/cf/api/appinstances"
+	"code.cloudfoundry.org/cli/cf/api/applications"
+	"code.cloudfoundry.org/cli/cf/api/organizations"
+	"code.cloudfoundry.org/cli/cf/api/spaces"
+	"github.com/mevansam/cf-cli-api/cfapi"
+)
+
+// NewRefreshingSession - Wraps the given session so that it is replaced
+// by a new session created with the given function whenever its access
+// token is about to expire and has been refreshed. The session loads its
+// token from the config file the refresher saves the new token to.
+func NewRefreshingSession(
+	session cfapi.CfSession,
+	refresher *TokenRefresher,
+	newSession func() (cfapi.CfSession, error),
+	debug func(format string, v ...interface{})) cfapi.CfSession {
+
+	return &refreshingSession{
+		CfSession:  session,
+		refresher:  refresher,
+		generation: refresher.Generation(),
+		newSession: newSession,
+		debug:      debug,
+	}
+}
+
+// refreshingSession - Checks the access token before handing out the
+// repositories used by the copy. Sessions load their access token when
+// they are created so a session whose token is older than the token of
+// the refresher, which may be shared with other sessions of the same
+// target, replaces the current session with its org and space.
+type refreshingSession struct {
+	cfapi.CfSession
+
+	refresher  *TokenRefresher
+	generation int
+	newSession func() (cfapi.CfSession, error)
+	debug      func(format string, v ...interface{})
+
+	mutex sync.Mutex
+}
+
+func (s *refreshingSession) current() cfapi.CfSession {
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, _, err := s.refresher.AccessToken(); err != nil {
+		s.debug("Unable to refresh access token: %s", err.Error())
+		return s.CfSession
+	}
+	if generation := s.refresher.Generation(); generation != s.generation {
+		session, err := s.newSession()
+		if err != nil {
+			s.debug("Unable to create session with refreshed access token: %s", err.Error())
+			return s.CfSession
+		}
+		session.SetSessionOrg(s.CfSession.GetSessionOrg())
+		session.SetSessionSpace(s.CfSession.GetSessionSpace())
+
+		// The replaced session is not closed as closing it
+		// could save its expired token to the config file
+		s.CfSession = session
+		s.generation = generation
+		s.debug("Refreshed access token of session for user '%s'", session.GetSessionUsername())
+	}
+	return s.CfSession
+}
+
+func (s *refreshingSession) Organizations() organizations.OrganizationRepository {
+	return s.current().Organizations()
+}
+
+func (s *refreshingSession) Spaces() spaces.SpaceRepository {
+	return s.current().Spaces()
+}
+
+func (s *refreshingSession) Domains() api.DomainRepository {
+	return s.current().Domains()
+}
+
+func (s *refreshingSession) Services() api.ServiceRepository {
+	return s.current().Services()
+}
+
+func (s *refreshingSession) ServiceBindings() api.ServiceBindingRepository {
+	return s.current().ServiceBindings()
+}
+
+func (s *refreshingSession) ServiceSummary() api.ServiceSummaryRepository {
+	return s.current().ServiceSummary()
+}
+
+func (s *refreshingSession) Applications() applications.Repository {
+	return s.current().Applications()
+}
+
+func (s *refreshingSession) AppSummary() api.AppSummaryRepository {
+	return s.current().AppSummary()
+}
+
+func (s *refreshingSession) AppInstances() appinstances.Repository {
+	return s.current().AppInstances()
+}
+
+func (s *refreshingSession) Routes() api.RouteRepository {
+	return s.current().Routes()
+}
+
+func (s *refreshingSession) DownloadAppContent(appGUID string, outputFile *os.File, asDroplet bool) error {
+	return s.current().DownloadAppContent(appGUID, outputFile, asDroplet)
+}
+
+func (s *refreshingSession) UploadDroplet(appGUID string, contentType string, dropletUploadRequest *os.File) error {
+	return s.current().UploadDroplet(appGUID, contentType, dropletUploadRequest)
+}
@@ -0,0 +1,119 @@
+package helpers
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RetryPolicy - Retries operations that fail with transient Cloud
+// Controller or blobstore errors using an exponential backoff with
+// jitter. A nil policy runs each operation once.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	debug func(format string, v ...interface{})
+}
+
+// NewRetryPolicy - Creates a policy which retries a failed
+// operation the given number of times. Retries are reported
+// to the given debug logger.
+func NewRetryPolicy(retries int, debug func(format string, v ...interface{})) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    retries + 1,
+		InitialBackoff: time.Second,
+		MaxBackoff:     30 * time.Second,
+		debug:          debug,
+	}
+}
+
+// Do - Runs the given operation until it succeeds, fails with an error
+// that is not transient or the maximum number of attempts is reached
+func (p *RetryPolicy) Do(operation string, fn func() error) (err error) {
+
+	if p == nil {
+		return fn()
+	}
+
+	backoff := p.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !IsRetryableError(err) {
+			return
+		}
+
+		// Sleep for between half and all of the backoff so
+		// that concurrent copies do not retry in lock step
+		delay := backoff / 2
+		if backoff > 1 {
+			delay += time.Duration(rand.Int63n(int64(backoff / 2)))
+		}
+		if p.debug != nil {
+			p.debug("Attempt %d of %d to %s failed, retrying in %s: %s",
+				attempt, p.MaxAttempts, operation, delay, err.Error())
+		}
+		time.Sleep(delay)
+
+		if backoff *= 2; backoff > p.MaxBackoff {
+			backoff = p.MaxBackoff
+		}
+	}
+}
+
+// IsRetryableError - Returns whether the given error is transient. These
+// are gateway errors and throttling responses from the Cloud Controller,
+// network timeouts and connections dropped while a response was read.
+func IsRetryableError(err error) bool {
+
+	if err == nil {
+		return false
+	}
+	if httpErr, ok := err.(interface {
+		StatusCode() int
+	}); ok {
+		return isRetryableStatus(httpErr.StatusCode())
+	}
+	if ccErr, ok := err.(*CCError); ok {
+		return isRetryableStatus(ccErr.StatusCode)
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if netErr, ok := err.(net.Error); ok && (netErr.Timeout() || netErr.Temporary()) {
+		return true
+	}
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return true
+	}
+
+	// The CLI's API clients wrap transport errors so
+	// they can only be recognized by their message
+	message := strings.ToLower(err.Error())
+	for _, transient := range []string{
+		"connection reset",
+		"connection refused",
+		"broken pipe",
+		"timeout",
+		"unexpected eof",
+		"502 bad gateway",
+		"503 service unavailable",
+		"504 gateway timeout",
+	} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case 429, 502, 503, 504:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package helpers
+
+import (
+	"io"
+	"os"
+
+	"code.cloudfoundry.org/cli/cf/api"
+	"code.cloudfoundry.org/cli/cf/api/appinstances"
+	"code.cloudfoundry.org/cli/cf/api/applications"
+	"code.cloudfoundry.org/cli/cf/models"
+	"github.com/mevansam/cf-cli-api/cfapi"
+)
+
+// NewRetrySession - Wraps the given session so that transient failures
+// of the calls made by the copy are retried using the given policy
+func NewRetrySession(session cfapi.CfSession, retry *RetryPolicy) cfapi.CfSession {
+	return &retrySession{CfSession: session, retry: retry}
+}
+
+// retrySession - Retries the idempotent calls of a session. Calls that
+// create resources are not retried as a request that timed out may
+// still have been completed by the Cloud Controller.
+type retrySession struct {
+	cfapi.CfSession
+	retry *RetryPolicy
+}
+
+func (s *retrySession) Applications() applications.Repository {
+	return &retryApplications{Repository: s.CfSession.Applications(), retry: s.retry}
+}
+
+func (s *retrySession) AppSummary() api.AppSummaryRepository {
+	return &retryAppSummary{AppSummaryRepository: s.CfSession.AppSummary(), retry: s.retry}
+}
+
+func (s *retrySession) AppInstances() appinstances.Repository {
+	return &retryAppInstances{Repository: s.CfSession.AppInstances(), retry: s.retry}
+}
+
+func (s *retrySession) Routes() api.RouteRepository {
+	return &retryRoutes{RouteRepository: s.CfSession.Routes(), retry: s.retry}
+}
+
+// DownloadAppContent - Restarts the download from the beginning
+// of the output file if it fails with a transient error
+func (s *retrySession) DownloadAppContent(appGUID string, outputFile *os.File, asDroplet bool) error {
+	return s.retry.Do("download app "+appGUID, func() (err error) {
+		if _, err = outputFile.Seek(0, io.SeekStart); err != nil {
+			return
+		}
+		if err = outputFile.Truncate(0); err != nil {
+			return
+		}
+		return s.CfSession.DownloadAppContent(appGUID, outputFile, asDroplet)
+	})
+}
+
+// UploadDroplet - Uploads the droplet again from the beginning
+// of the request file if it fails with a transient error
+func (s *retrySession) UploadDroplet(appGUID string, contentType string, dropletUploadRequest *os.File) error {
+	return s.retry.Do("upload droplet of app "+appGUID, func() (err error) {
+		if _, err = dropletUploadRequest.Seek(0, io.SeekStart); err != nil {
+			return
+		}
+		return s.CfSession.UploadDroplet(appGUID, contentType, dropletUploadRequest)
+	})
+}
+
+type retryApplications struct {
+	applications.Repository
+	retry *RetryPolicy
+}
+
+func (r *retryApplications) Read(name string) (app models.Application, err error) {
+	err = r.retry.Do("read app "+name, func() (err error) {
+		app, err = r.Repository.Read(name)
+		return
+	})
+	return
+}
+
+func (r *retryApplications) GetApp(appGUID string) (app models.Application, err error) {
+	err = r.retry.Do("get app "+appGUID, func() (err error) {
+		app, err = r.Repository.GetApp(appGUID)
+		return
+	})
+	return
+}
+
+func (r *retryApplications) Update(appGUID string, params models.AppParams) (app models.Application, err error) {
+	err = r.retry.Do("update app "+appGUID, func() (err error) {
+		app, err = r.Repository.Update(appGUID, params)
+		return
+	})
+	return
+}
+
+type retryAppSummary struct {
+	api.AppSummaryRepository
+	retry *RetryPolicy
+}
+
+func (r *retryAppSummary) GetSummariesInCurrentSpace() (apps []models.Application, err error) {
+	err = r.retry.Do("get app summaries", func() (err error) {
+		apps, err = r.AppSummaryRepository.GetSummariesInCurrentSpace()
+		return
+	})
+	return
+}
+
+func (r *retryAppSummary) GetSummary(appGUID string) (app models.Application, err error) {
+	err = r.retry.Do("get summary of app "+appGUID, func() (err error) {
+		app, err = r.AppSummaryRepository.GetSummary(appGUID)
+		return
+	})
+	return
+}
+
+type retryAppInstances struct {
+	appinstances.Repository
+	retry *RetryPolicy
+}
+
+func (r *retryAppInstances) GetInstances(appGUID string) (instances []models.AppInstanceFields, err error) {
+	err = r.retry.Do("get instances of app "+appGUID, func() (err error) {
+		instances, err = r.Repository.GetInstances(appGUID)
+		return
+	})
+	return
+}
+
+type retryRoutes struct {
+	api.RouteRepository
+	retry *RetryPolicy
+}
+
+func (r *retryRoutes) Find(host string, domain models.DomainFields, path string, port int) (route models.Route, err error) {
+	err = r.retry.Do("find route "+host+"."+domain.Name, func() (err error) {
+		route, err = r.RouteRepository.Find(host, domain, path, port)
+		return
+	})
+	return
+}
+
+func (r *retryRoutes) Bind(routeGUID, appGUID string) error {
+	return r.retry.Do("bind route "+routeGUID, func() error {
+		return r.RouteRepository.Bind(routeGUID, appGUID)
+	})
+}
+
+func (r *retryRoutes) Unbind(routeGUID, appGUID string) error {
+	return r.retry.Do("unbind route "+routeGUID, func() error {
+		return r.RouteRepository.Unbind(routeGUID, appGUID)
+	})
+}
@@ -0,0 +1,59 @@
+package helpers_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retry Tests", func() {
+
+	var policy *RetryPolicy
+
+	BeforeEach(func() {
+		policy = NewRetryPolicy(2, nil)
+		policy.InitialBackoff = time.Millisecond
+	})
+
+	It("Retries transient errors until the operation succeeds", func() {
+		attempts := 0
+		err := policy.Do("test", func() error {
+			if attempts++; attempts < 3 {
+				return &CCError{StatusCode: 502}
+			}
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Gives up after the maximum number of attempts", func() {
+		attempts := 0
+		err := policy.Do("test", func() error {
+			attempts++
+			return &CCError{StatusCode: 503}
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(attempts).To(Equal(3))
+	})
+
+	It("Does not retry errors that are not transient", func() {
+		attempts := 0
+		err := policy.Do("test", func() error {
+			attempts++
+			return &CCError{StatusCode: 404}
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(attempts).To(Equal(1))
+	})
+
+	It("Classifies errors", func() {
+		Expect(IsRetryableError(errors.New("read tcp 10.0.0.1:443: connection reset by peer"))).To(BeTrue())
+		Expect(IsRetryableError(&CCError{StatusCode: 429})).To(BeTrue())
+		Expect(IsRetryableError(&CCError{StatusCode: 422})).To(BeFalse())
+		Expect(IsRetryableError(errors.New("App name is taken"))).To(BeFalse())
+	})
+})
@@ -0,0 +1,64 @@
+package helpers
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// routeCheckTimeout - How long a single request to a route may take
+const routeCheckTimeout = 10 * time.Second
+
+// RouteChecker - Checks whether the applications mapped
+// to HTTP routes respond to requests sent to them
+type RouteChecker struct {
+	httpClient *http.Client
+}
+
+// NewRouteChecker - Creates a route checker. Certificates of the
+// routes are not validated if SSL validation is disabled.
+func NewRouteChecker(sslDisabled bool) *RouteChecker {
+	return &RouteChecker{
+		httpClient: &http.Client{
+			Timeout: routeCheckTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: sslDisabled},
+			},
+			// A redirect is a response of the application
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Check - Returns an error unless an application responds to the given
+// route. The route is given without a scheme and is requested using
+// HTTPS first and then HTTP. Any response other than a server error or
+// an error of the router in front of the application is accepted.
+func (r *RouteChecker) Check(route string) (err error) {
+
+	var response *http.Response
+
+	for _, scheme := range []string{"https://", "http://"} {
+		if response, err = r.httpClient.Get(scheme + route); err != nil {
+			continue
+		}
+		io.Copy(ioutil.Discard, response.Body)
+		response.Body.Close()
+
+		if routerError := response.Header.Get("X-Cf-Routererror"); routerError != "" {
+			return fmt.Errorf("the router responded with '%s'", routerError)
+		}
+		if response.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("the app responded with status %d", response.StatusCode)
+		}
+		return nil
+	}
+	return fmt.Errorf("the route cannot be reached: %s", strings.TrimSpace(err.Error()))
+}
@@ -0,0 +1,52 @@
+package helpers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Route Check Tests", func() {
+
+	var (
+		server  *httptest.Server
+		checker *RouteChecker
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/unknown":
+				w.Header().Set("X-Cf-Routererror", "unknown_route")
+				w.WriteHeader(http.StatusNotFound)
+			case "/failing":
+				w.WriteHeader(http.StatusBadGateway)
+			case "/login":
+				http.Redirect(w, r, "/", http.StatusFound)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		checker = NewRouteChecker(true)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("Accepts any response of the app", func() {
+		route := strings.TrimPrefix(server.URL, "http://")
+		Expect(checker.Check(route + "/missing")).To(Succeed())
+		Expect(checker.Check(route + "/login")).To(Succeed())
+	})
+
+	It("Rejects server and router errors", func() {
+		route := strings.TrimPrefix(server.URL, "http://")
+		Expect(checker.Check(route + "/failing")).To(MatchError("the app responded with status 502"))
+		Expect(checker.Check(route + "/unknown")).To(MatchError("the router responded with 'unknown_route'"))
+	})
+})
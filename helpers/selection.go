@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseSelection - Parses the items picked from a numbered list of the
+// given length. The selection is a comma or space separated list of item
+// numbers and ranges such as "1,3-5", or "all" or "none". The zero based
+// indexes of the picked items are returned in list order.
+func ParseSelection(selection string, count int) ([]int, error) {
+
+	selection = strings.ToLower(strings.TrimSpace(selection))
+	switch selection {
+	case "all":
+		indexes := make([]int, count)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	case "none":
+		return []int{}, nil
+	case "":
+		return nil, fmt.Errorf("nothing was selected")
+	}
+
+	picked := make(map[int]bool)
+	for _, item := range strings.FieldsFunc(selection, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t'
+	}) {
+		from, to := item, item
+		if i := strings.Index(item, "-"); i > 0 {
+			from, to = item[:i], item[i+1:]
+		}
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection '%s'", item)
+		}
+		last, err := strconv.Atoi(to)
+		if err != nil || last < first {
+			return nil, fmt.Errorf("invalid selection '%s'", item)
+		}
+		if first < 1 || last > count {
+			return nil, fmt.Errorf("selection '%s' is not between 1 and %d", item, count)
+		}
+		for n := first; n <= last; n++ {
+			picked[n-1] = true
+		}
+	}
+
+	indexes := []int{}
+	for i := range picked {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
@@ -0,0 +1,37 @@
+package helpers_test
+
+import (
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Selection Tests", func() {
+
+	It("Parses item numbers and ranges", func() {
+		indexes, err := ParseSelection("5, 1-3 2", 6)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(indexes).To(Equal([]int{0, 1, 2, 4}))
+	})
+
+	It("Parses all and none", func() {
+		indexes, err := ParseSelection("All", 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(indexes).To(Equal([]int{0, 1, 2}))
+
+		indexes, err = ParseSelection("none", 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(indexes).To(BeEmpty())
+	})
+
+	It("Rejects invalid selections", func() {
+		_, err := ParseSelection("", 3)
+		Expect(err).To(MatchError("nothing was selected"))
+		_, err = ParseSelection("1,web", 3)
+		Expect(err).To(MatchError("invalid selection 'web'"))
+		_, err = ParseSelection("3-1", 3)
+		Expect(err).To(MatchError("invalid selection '3-1'"))
+		_, err = ParseSelection("2-4", 3)
+		Expect(err).To(MatchError("selection '2-4' is not between 1 and 3"))
+	})
+})
@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/cli/cf/api"
+	"code.cloudfoundry.org/cli/cf/errors"
+	"code.cloudfoundry.org/cli/cf/models"
+	"github.com/mevansam/cf-cli-api/cfapi"
+)
+
+// ServiceFilter - The names of the service instances that are
+// hidden from the sessions created with it
+type ServiceFilter struct {
+	excluded map[string]bool
+	mutex    sync.Mutex
+}
+
+// NewServiceFilter -
+func NewServiceFilter() *ServiceFilter {
+	return &ServiceFilter{excluded: make(map[string]bool)}
+}
+
+// Exclude - Replaces the names of the service instances to hide
+func (f *ServiceFilter) Exclude(names []string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.excluded = make(map[string]bool)
+	for _, n := range names {
+		f.excluded[n] = true
+	}
+}
+
+// Excluded - Returns the names of the hidden service instances
+func (f *ServiceFilter) Excluded() (names []string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for n := range f.excluded {
+		names = append(names, n)
+	}
+	return
+}
+
+func (f *ServiceFilter) isExcluded(name string) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.excluded[name]
+}
+
+// NewServiceFilterSession - Wraps the given session so that the service
+// instances excluded by the given filter are not seen through it
+func NewServiceFilterSession(session cfapi.CfSession, filter *ServiceFilter) cfapi.CfSession {
+	return &serviceFilterSession{CfSession: session, filter: filter}
+}
+
+// serviceFilterSession - The services manager collects the services
+// to be copied from the service instances in the source space so hiding
+// a service instance from the manager's source session leaves it out
+// of the services that are copied.
+type serviceFilterSession struct {
+	cfapi.CfSession
+	filter *ServiceFilter
+}
+
+func (s *serviceFilterSession) ServiceSummary() api.ServiceSummaryRepository {
+	return &filteredServiceSummary{ServiceSummaryRepository: s.CfSession.ServiceSummary(), filter: s.filter}
+}
+
+func (s *serviceFilterSession) Services() api.ServiceRepository {
+	return &filteredServices{ServiceRepository: s.CfSession.Services(), filter: s.filter}
+}
+
+type filteredServiceSummary struct {
+	api.ServiceSummaryRepository
+	filter *ServiceFilter
+}
+
+func (r *filteredServiceSummary) GetSummariesInCurrentSpace() (instances []models.ServiceInstance, err error) {
+
+	var all []models.ServiceInstance
+
+	if all, err = r.ServiceSummaryRepository.GetSummariesInCurrentSpace(); err != nil {
+		return
+	}
+	for _, i := range all {
+		if !r.filter.isExcluded(i.Name) {
+			instances = append(instances, i)
+		}
+	}
+	return
+}
+
+type filteredServices struct {
+	api.ServiceRepository
+	filter *ServiceFilter
+}
+
+func (r *filteredServices) FindInstanceByName(name string) (models.ServiceInstance, error) {
+	if r.filter.isExcluded(name) {
+		return models.ServiceInstance{}, errors.NewModelNotFoundError("Service instance", name)
+	}
+	return r.ServiceRepository.FindInstanceByName(name)
+}
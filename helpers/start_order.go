@@ -0,0 +1,124 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ReadStartOrderFile - Reads application start tiers from the
+// given file. Each line is a tier of comma or space separated
+// application names. Blank lines and lines starting with '#'
+// are ignored.
+func ReadStartOrderFile(filePath string) (tiers [][]string, err error) {
+
+	var file *os.File
+
+	if file, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tier := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		tiers = append(tiers, tier)
+	}
+	err = scanner.Err()
+	return
+}
+
+// OrderStartTiers - Groups the given applications into the given tiers
+// keeping the order of the tiers. Applications in tiers that are not in
+// the list are dropped as are tiers left empty. Applications missing
+// from the tiers are added to a final tier.
+func OrderStartTiers(appNames []string, tiers [][]string) [][]string {
+
+	apps := make(map[string]bool)
+	for _, n := range appNames {
+		apps[n] = true
+	}
+
+	ordered := [][]string{}
+	for _, tier := range tiers {
+		t := []string{}
+		for _, n := range tier {
+			if apps[n] {
+				t = append(t, n)
+				delete(apps, n)
+			}
+		}
+		if len(t) > 0 {
+			ordered = append(ordered, t)
+		}
+	}
+
+	remaining := []string{}
+	for _, n := range appNames {
+		if apps[n] {
+			remaining = append(remaining, n)
+		}
+	}
+	if len(remaining) > 0 {
+		ordered = append(ordered, remaining)
+	}
+	return ordered
+}
+
+// DependencyTiers - Groups the given applications into tiers from
+// a map of each application to the applications it depends on.
+func DependencyTiers(appNames []string, dependsOn map[string][]string) ([][]string, error) {
+
+	apps := make(map[string]bool)
+	for _, n := range appNames {
+		apps[n] = true
+	}
+
+	tiers := [][]string{}
+	started := make(map[string]bool)
+
+	for len(started) < len(apps) {
+
+		tier := []string{}
+		for n := range apps {
+			if started[n] {
+				continue
+			}
+			ready := true
+			for _, d := range dependsOn[n] {
+				if apps[d] && d != n && !started[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				tier = append(tier, n)
+			}
+		}
+		if len(tier) == 0 {
+			pending := []string{}
+			for n := range apps {
+				if !started[n] {
+					pending = append(pending, n)
+				}
+			}
+			sort.Strings(pending)
+			return nil, fmt.Errorf("circular dependency between apps '%s'", strings.Join(pending, "', '"))
+		}
+
+		sort.Strings(tier)
+		for _, n := range tier {
+			started[n] = true
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, nil
+}
@@ -0,0 +1,50 @@
+package helpers_test
+
+import (
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Start Order Tests", func() {
+
+	It("Orders apps by their dependencies", func() {
+		tiers, err := DependencyTiers(
+			[]string{"web", "api", "db-proxy", "worker"},
+			map[string][]string{
+				"web":    []string{"api"},
+				"api":    []string{"db-proxy", "external-app"},
+				"worker": []string{"db-proxy"},
+			})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tiers).To(Equal([][]string{
+			[]string{"db-proxy"},
+			[]string{"api", "worker"},
+			[]string{"web"},
+		}))
+	})
+
+	It("Recognizes circular dependencies", func() {
+		_, err := DependencyTiers(
+			[]string{"web", "api", "db-proxy"},
+			map[string][]string{
+				"api":      []string{"db-proxy"},
+				"db-proxy": []string{"api"},
+			})
+		Expect(err).To(MatchError("circular dependency between apps 'api', 'db-proxy'"))
+	})
+
+	It("Orders apps by the given tiers", func() {
+		tiers := OrderStartTiers(
+			[]string{"web", "api", "db-proxy", "worker"},
+			[][]string{
+				[]string{"db-proxy", "unknown"},
+				[]string{"api"},
+			})
+		Expect(tiers).To(Equal([][]string{
+			[]string{"db-proxy"},
+			[]string{"api"},
+			[]string{"web", "worker"},
+		}))
+	})
+})
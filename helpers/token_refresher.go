@@ -0,0 +1,240 @@
+package helpers
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenExpiryMargin - Tokens are refreshed when they expire within this
+// margin so that requests started with them do not fail part way
+const tokenExpiryMargin = 5 * time.Minute
+
+// TokenRefresher - Refreshes the OAuth access token saved in a CLI config
+// file using its refresh token and saves the new tokens back to the file
+type TokenRefresher struct {
+	configPath string
+
+	accessToken  string
+	refreshToken string
+	tokenURL     string
+	clientID     string
+	clientSecret string
+
+	// The number of times the token has been refreshed
+	generation int
+
+	httpClient *http.Client
+	mutex      sync.Mutex
+}
+
+// tokenConfig - The fields of a CLI config file used to refresh tokens
+type tokenConfig struct {
+	AccessToken          string
+	RefreshToken         string
+	UaaEndpoint          string
+	UAAOAuthClient       string
+	UAAOAuthClientSecret string
+	SSLDisabled          bool
+}
+
+// NewTokenRefresher - Creates a refresher for the tokens saved in
+// the given CLI config file. The file must have a refresh token.
+func NewTokenRefresher(configPath string) (*TokenRefresher, error) {
+
+	var config tokenConfig
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	if config.RefreshToken == "" || config.UaaEndpoint == "" {
+		return nil, fmt.Errorf("no refresh token or UAA endpoint is set in '%s'", configPath)
+	}
+	if config.UAAOAuthClient == "" {
+		config.UAAOAuthClient = "cf"
+	}
+
+	return &TokenRefresher{
+		configPath:   configPath,
+		accessToken:  config.AccessToken,
+		refreshToken: config.RefreshToken,
+		tokenURL:     strings.TrimSuffix(config.UaaEndpoint, "/") + "/oauth/token",
+		clientID:     config.UAAOAuthClient,
+		clientSecret: config.UAAOAuthClientSecret,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: config.SSLDisabled},
+			},
+		},
+	}, nil
+}
+
+// AccessToken - Returns an access token that is valid for at least the
+// expiry margin, refreshing it if required. The second return value is
+// true if the token was refreshed.
+func (r *TokenRefresher) AccessToken() (string, bool, error) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !tokenExpiresBefore(r.accessToken, time.Now().Add(tokenExpiryMargin)) {
+		return r.accessToken, false, nil
+	}
+	if err := r.refresh(); err != nil {
+		return "", false, err
+	}
+	return r.accessToken, true, nil
+}
+
+// Refresh - Refreshes the access token regardless of its expiry. This is
+// used when a request is rejected because the token has been revoked.
+func (r *TokenRefresher) Refresh() (string, error) {
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := r.refresh(); err != nil {
+		return "", err
+	}
+	return r.accessToken, nil
+}
+
+// Generation - Returns the number of times the access token has been
+// refreshed. Users of the token that hold on to it compare it with the
+// generation their token was taken from to tell when to reload it.
+func (r *TokenRefresher) Generation() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.generation
+}
+
+func (r *TokenRefresher) refresh() (err error) {
+
+	var (
+		request  *http.Request
+		response *http.Response
+		token    struct {
+			AccessToken  string `json:"access_token"`
+			RefreshToken string `json:"refresh_token"`
+			TokenType    string `json:"token_type"`
+		}
+	)
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", r.refreshToken)
+
+	if request, err = http.NewRequest("POST", r.tokenURL, strings.NewReader(form.Encode())); err != nil {
+		return
+	}
+	request.SetBasicAuth(r.clientID, r.clientSecret)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	if response, err = r.httpClient.Do(request); err != nil {
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("refreshing the access token for '%s' failed with status %d: %s",
+			r.configPath, response.StatusCode, string(data))
+	}
+	if err = json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return
+	}
+	if token.TokenType == "" {
+		token.TokenType = "bearer"
+	}
+
+	r.accessToken = strings.ToLower(token.TokenType) + " " + token.AccessToken
+	if token.RefreshToken != "" {
+		r.refreshToken = token.RefreshToken
+	}
+	r.generation++
+	return r.save()
+}
+
+// save - Writes the tokens to the config file leaving all
+// other fields of the file as they are
+func (r *TokenRefresher) save() (err error) {
+
+	var (
+		data   []byte
+		config map[string]json.RawMessage
+		info   os.FileInfo
+		file   *os.File
+	)
+
+	if info, err = os.Stat(r.configPath); err != nil {
+		return
+	}
+	if data, err = ioutil.ReadFile(r.configPath); err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &config); err != nil {
+		return
+	}
+	if config["AccessToken"], err = json.Marshal(r.accessToken); err != nil {
+		return
+	}
+	if config["RefreshToken"], err = json.Marshal(r.refreshToken); err != nil {
+		return
+	}
+	if data, err = json.MarshalIndent(config, "", "  "); err != nil {
+		return
+	}
+
+	// Replace the file in one step so that a
+	// CLI reading it never sees a partial file
+	if file, err = ioutil.TempFile(filepath.Dir(r.configPath), ".config"); err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = file.Write(data); err == nil {
+		err = file.Chmod(info.Mode())
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+	return os.Rename(file.Name(), r.configPath)
+}
+
+// tokenExpiresBefore - Returns whether the given bearer token expires
+// before the given time. Tokens that cannot be decoded are treated as
+// expired so that they are refreshed.
+func tokenExpiresBefore(token string, t time.Time) bool {
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+
+	parts := strings.Split(strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(token, "bearer "), "Bearer ")), ".")
+	if len(parts) != 3 {
+		return true
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil || json.Unmarshal(data, &claims) != nil || claims.Exp == 0 {
+		return true
+	}
+	return time.Unix(claims.Exp, 0).Before(t)
+}
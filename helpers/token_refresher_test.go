@@ -0,0 +1,146 @@
+package helpers_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/api/applications"
+	"code.cloudfoundry.org/cli/cf/api/applications/applicationsfakes"
+	"code.cloudfoundry.org/cli/cf/models"
+	"github.com/mevansam/cf-cli-api/cfapi"
+	"github.com/mevansam/cf-cli-api/cfapi/mocks"
+	. "github.com/mevansam/cf-copy-plugin/helpers"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token Refresher Tests", func() {
+
+	var (
+		dir        string
+		configPath string
+		uaa        *httptest.Server
+		newToken   string
+	)
+
+	jwt := func(expiry time.Time) string {
+		claims, _ := json.Marshal(map[string]int64{"exp": expiry.Unix()})
+		return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(claims) + ".c2lnbmF0dXJl"
+	}
+
+	writeConfig := func(accessToken string) {
+		data, _ := json.Marshal(map[string]interface{}{
+			"Target":       "https://api.example.com",
+			"UaaEndpoint":  uaa.URL,
+			"AccessToken":  "bearer " + accessToken,
+			"RefreshToken": "fake_refresh_token",
+			"SpaceFields":  map[string]string{"Name": "fake_space"},
+		})
+		Expect(ioutil.WriteFile(configPath, data, 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "token-refresher")
+		configPath = filepath.Join(dir, "config.json")
+		newToken = jwt(time.Now().Add(time.Hour))
+
+		uaa = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			if r.URL.Path != "/oauth/token" ||
+				r.Form.Get("grant_type") != "refresh_token" ||
+				r.Form.Get("refresh_token") != "fake_refresh_token" {
+
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"access_token":"%s","refresh_token":"new_refresh_token","token_type":"bearer"}`, newToken)
+		}))
+	})
+
+	AfterEach(func() {
+		uaa.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("Does not refresh a token that is valid", func() {
+		token := jwt(time.Now().Add(time.Hour))
+		writeConfig(token)
+
+		refresher, err := NewTokenRefresher(configPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken, refreshed, err := refresher.AccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refreshed).To(BeFalse())
+		Expect(accessToken).To(Equal("bearer " + token))
+	})
+
+	It("Refreshes an expiring token and saves it to the config file", func() {
+		writeConfig(jwt(time.Now().Add(time.Minute)))
+
+		refresher, err := NewTokenRefresher(configPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		accessToken, refreshed, err := refresher.AccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refreshed).To(BeTrue())
+		Expect(accessToken).To(Equal("bearer " + newToken))
+
+		config := map[string]interface{}{}
+		data, _ := ioutil.ReadFile(configPath)
+		Expect(json.Unmarshal(data, &config)).To(Succeed())
+		Expect(config["AccessToken"]).To(Equal("bearer " + newToken))
+		Expect(config["RefreshToken"]).To(Equal("new_refresh_token"))
+		Expect(config["SpaceFields"]).To(Equal(map[string]interface{}{"Name": "fake_space"}))
+	})
+
+	It("Replaces each session sharing a refresher once the token is refreshed", func() {
+		writeConfig(jwt(time.Now().Add(time.Minute)))
+
+		refresher, err := NewTokenRefresher(configPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refresher.Generation()).To(Equal(0))
+
+		newSession := func(sessions *int) func() (cfapi.CfSession, error) {
+			return func() (cfapi.CfSession, error) {
+				*sessions++
+				return &mocks.MockSession{
+					MockSetSessionOrg:      func(org models.OrganizationFields) {},
+					MockSetSessionSpace:    func(space models.SpaceFields) {},
+					MockGetSessionUsername: func() string { return "fake_user" },
+					MockApplications:       func() applications.Repository { return &applicationsfakes.FakeRepository{} },
+				}, nil
+			}
+		}
+		oldSession := func() *mocks.MockSession {
+			return &mocks.MockSession{
+				MockGetSessionOrg:   func() models.OrganizationFields { return models.OrganizationFields{} },
+				MockGetSessionSpace: func() models.SpaceFields { return models.SpaceFields{} },
+			}
+		}
+		debug := func(format string, v ...interface{}) {}
+
+		srcSessions, destSessions := 0, 0
+		srcSession := NewRefreshingSession(oldSession(), refresher, newSession(&srcSessions), debug)
+		destSession := NewRefreshingSession(oldSession(), refresher, newSession(&destSessions), debug)
+
+		srcSession.Applications()
+		Expect(refresher.Generation()).To(Equal(1))
+		Expect(srcSessions).To(Equal(1))
+
+		destSession.Applications()
+		Expect(destSessions).To(Equal(1))
+
+		srcSession.Applications()
+		destSession.Applications()
+		Expect(srcSessions).To(Equal(1))
+		Expect(destSessions).To(Equal(1))
+	})
+})